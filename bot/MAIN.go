@@ -1,16 +1,22 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"image"
 	"image/color"
 	_ "image/png"
 	"log"
 	"math/rand"
+	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"time"
 
+	"your_project/canvas"
+	"your_project/codec"
+
 	"github.com/gorilla/websocket"
 )
 
@@ -20,9 +26,13 @@ type Pixel struct {
 	Color string `json:"color"`
 }
 
-type Message struct {
-	Type  string `json:"type"`
-	Pixel Pixel  `json:"pixel"`
+// Reply - ответ сервера на установку пикселя (ack, cooldown или error)
+type Reply struct {
+	Type        string `json:"type"`
+	ID          string `json:"id"`
+	RemainingMs int64  `json:"remainingMs"`
+	Code        string `json:"code"`
+	Error       string `json:"error"`
 }
 
 func colorToHex(c color.Color) string {
@@ -97,8 +107,93 @@ func generateRandomPixels(width, height, count int) []Pixel {
 	return pixels
 }
 
+type Canvas struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// fetchCanvas запрашивает размеры холста у сервера
+func fetchCanvas(apiURL string) (Canvas, error) {
+	var canvas Canvas
+	resp, err := http.Get(apiURL)
+	if err != nil {
+		return canvas, err
+	}
+	defer resp.Body.Close()
+
+	err = json.NewDecoder(resp.Body).Decode(&canvas)
+	return canvas, err
+}
+
+// fetchPalette загружает палитру сервера: он принимает только её цвета
+func fetchPalette(apiURL string) (*canvas.Palette, error) {
+	resp, err := http.Get(apiURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Colors []string `json:"colors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	return canvas.NewPalette(body.Colors)
+}
+
+// snapToPalette заменяет цвета пикселей на ближайшие цвета палитры
+func snapToPalette(pixels []Pixel, palette *canvas.Palette) {
+	for i := range pixels {
+		c, err := canvas.ParseHexColor(pixels[i].Color)
+		if err != nil {
+			continue
+		}
+		pixels[i].Color, _ = palette.Hex(palette.Nearest(c))
+	}
+}
+
+// decodeBinaryReply разбирает бинарные ack и cooldown; пачки обновлений пропускаются
+func decodeBinaryReply(frame []byte) (Reply, bool) {
+	frameType, err := codec.FrameType(frame)
+	if err != nil {
+		return Reply{}, false
+	}
+	switch frameType {
+	case codec.FrameAck:
+		id, err := codec.DecodeAck(frame)
+		if err != nil {
+			return Reply{}, false
+		}
+		return Reply{Type: "ack", ID: strconv.FormatUint(uint64(id), 10)}, true
+	case codec.FrameCooldown:
+		id, remaining, err := codec.DecodeCooldown(frame)
+		if err != nil {
+			return Reply{}, false
+		}
+		return Reply{
+			Type:        "cooldown",
+			ID:          strconv.FormatUint(uint64(id), 10),
+			RemainingMs: remaining.Milliseconds(),
+		}, true
+	}
+	return Reply{}, false
+}
+
+// waitReply ждёт ответ сервера на сообщение с указанным id
+func waitReply(replies <-chan Reply, id string) (Reply, bool) {
+	for reply := range replies {
+		if reply.ID == id {
+			return reply, true
+		}
+	}
+	return Reply{}, false
+}
+
 func main() {
-	serverURL := "ws://localhost:8080/ws/send"
+	serverURL := "ws://localhost:8080/ws"
+	canvasURL := "http://localhost:8080/api/canvas"
+	paletteURL := "http://localhost:8080/api/palette"
 	imageFiles := []struct {
 		Filename string
 		OffsetX  int
@@ -119,9 +214,19 @@ func main() {
 	}
 
 	// Добавление случайных пикселей
-	randomPixels := generateRandomPixels(500, 300, 1000) // Указываем размеры канваса и количество случайных пикселей
+	canvasSize, err := fetchCanvas(canvasURL)
+	if err != nil {
+		log.Fatal("Error fetching canvas size:", err)
+	}
+	randomPixels := generateRandomPixels(canvasSize.Width, canvasSize.Height, 1000) // Количество случайных пикселей
 	allPixels = append(allPixels, randomPixels...)
 
+	palette, err := fetchPalette(paletteURL)
+	if err != nil {
+		log.Fatal("Error fetching palette:", err)
+	}
+	snapToPalette(allPixels, palette)
+
 	// Перемешивание всех пикселей
 	shufflePixels(allPixels)
 
@@ -130,42 +235,105 @@ func main() {
 		log.Fatal("Invalid WebSocket URL:", err)
 	}
 
-	conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	dialer := websocket.Dialer{
+		HandshakeTimeout: websocket.DefaultDialer.HandshakeTimeout,
+		Proxy:            websocket.DefaultDialer.Proxy,
+		// Бинарный формат фреймов
+		Subprotocols: []string{codec.Subprotocol},
+	}
+	header := http.Header{}
+
+	// API-ключ кошелька, от имени которого рисует бот (POST /api/keys).
+	// JWT из PIXEL_TOKEN тоже подходит, но живёт недолго
+	if apiKey := os.Getenv("PIXEL_API_KEY"); apiKey != "" {
+		header.Set("X-API-Key", apiKey)
+	} else if token := os.Getenv("PIXEL_TOKEN"); token != "" {
+		// Токен передаётся следом за "bearer"
+		dialer.Subprotocols = append(dialer.Subprotocols, "bearer", token)
+	} else {
+		log.Fatal("PIXEL_API_KEY is not set")
+	}
+
+	conn, _, err := dialer.Dial(u.String(), header)
 	if err != nil {
 		log.Fatal("Error connecting to WebSocket server:", err)
 	}
 	defer conn.Close()
+	if conn.Subprotocol() != codec.Subprotocol {
+		log.Fatal("Server does not support the binary protocol")
+	}
 
+	// Сервер отвечает на каждый пиксель: ack, cooldown или error.
+	// Остальные сообщения (рассылка обновлений холста) боту не нужны
+	replies := make(chan Reply, 16)
 	go func() {
+		defer close(replies)
 		for {
-			_, message, err := conn.ReadMessage()
+			messageType, frame, err := conn.ReadMessage()
 			if err != nil {
 				log.Println("Error reading message:", err)
-				break
+				return
+			}
+			if messageType == websocket.BinaryMessage {
+				if reply, ok := decodeBinaryReply(frame); ok {
+					replies <- reply
+				}
+				continue
+			}
+			// Ошибки приходят текстом в JSON
+			for _, message := range bytes.Split(frame, []byte{'\n'}) {
+				var reply Reply
+				if json.Unmarshal(message, &reply) != nil || reply.ID == "" {
+					continue
+				}
+				replies <- reply
 			}
-			log.Printf("Received: %s\n", message)
 		}
 	}()
 
-	for _, pixel := range allPixels {
-		message := Message{
-			Type:  "update",
-			Pixel: pixel,
-		}
+	delay := 5 * time.Second
+	if v, err := time.ParseDuration(os.Getenv("PIXEL_DELAY")); err == nil {
+		delay = v
+	}
 
-		messageJSON, err := json.Marshal(message)
-		if err != nil {
-			log.Println("Error marshaling message:", err)
+	for i := 0; i < len(allPixels); i++ {
+		pixel := allPixels[i]
+		colorIndex, ok := palette.Index(pixel.Color)
+		if !ok {
+			continue
+		}
+		if pixel.X < 0 || pixel.Y < 0 || pixel.X >= canvasSize.Width || pixel.Y >= canvasSize.Height {
 			continue
 		}
 
-		err = conn.WriteMessage(websocket.TextMessage, messageJSON)
+		frame := codec.EncodePlace(uint32(i), codec.Record{
+			X:     uint16(pixel.X),
+			Y:     uint16(pixel.Y),
+			Color: uint8(colorIndex),
+		})
+		err = conn.WriteMessage(websocket.BinaryMessage, frame)
 		if err != nil {
 			log.Println("Error writing message:", err)
 			break
 		}
 
-		time.Sleep(5 * time.Millisecond)
+		reply, ok := waitReply(replies, strconv.Itoa(i))
+		if !ok {
+			log.Println("Connection closed")
+			break
+		}
+		switch reply.Type {
+		case "cooldown":
+			// Повторяем тот же пиксель после окончания паузы
+			time.Sleep(time.Duration(reply.RemainingMs) * time.Millisecond)
+			i--
+			continue
+		case "error":
+			log.Printf("Pixel %d rejected: %s (%s)\n", i, reply.Error, reply.Code)
+			continue
+		}
+
+		time.Sleep(delay)
 	}
 
 	log.Println("Bot finished painting!")
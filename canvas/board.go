@@ -0,0 +1,105 @@
+package canvas
+
+import (
+	"bytes"
+	"compress/gzip"
+	"sync"
+)
+
+// Empty - значение клетки, в которую ещё ничего не ставили
+const Empty byte = 0xFF
+
+// Board - состояние холста в памяти: по байту (индексу палитры) на клетку,
+// построчно слева направо, сверху вниз
+type Board struct {
+	width  int
+	height int
+
+	mutex   sync.RWMutex
+	cells   []byte
+	version uint64
+
+	snapshotMutex   sync.Mutex
+	snapshot        []byte
+	snapshotVersion uint64
+}
+
+func NewBoard(width, height int) *Board {
+	cells := make([]byte, width*height)
+	for i := range cells {
+		cells[i] = Empty
+	}
+	return &Board{
+		width:  width,
+		height: height,
+		cells:  cells,
+	}
+}
+
+func (b *Board) Width() int {
+	return b.width
+}
+
+func (b *Board) Height() int {
+	return b.height
+}
+
+// Set записывает индекс палитры в клетку и возвращает новую версию холста
+func (b *Board) Set(x, y int, index byte) uint64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if x < 0 || y < 0 || x >= b.width || y >= b.height {
+		return b.version
+	}
+	b.cells[y*b.width+x] = index
+	b.version++
+	return b.version
+}
+
+func (b *Board) At(x, y int) byte {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	if x < 0 || y < 0 || x >= b.width || y >= b.height {
+		return Empty
+	}
+	return b.cells[y*b.width+x]
+}
+
+func (b *Board) Version() uint64 {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.version
+}
+
+// Cells возвращает копию клеток вместе с версией, к которой она относится
+func (b *Board) Cells() ([]byte, uint64) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return append([]byte(nil), b.cells...), b.version
+}
+
+// Snapshot возвращает сжатые gzip клетки холста. Результат кешируется до следующего изменения
+func (b *Board) Snapshot() ([]byte, uint64, error) {
+	b.snapshotMutex.Lock()
+	defer b.snapshotMutex.Unlock()
+
+	if b.snapshot != nil && b.snapshotVersion == b.Version() {
+		return b.snapshot, b.snapshotVersion, nil
+	}
+
+	cells, version := b.Cells()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(cells); err != nil {
+		return nil, 0, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, 0, err
+	}
+
+	b.snapshot = buf.Bytes()
+	b.snapshotVersion = version
+	return b.snapshot, version, nil
+}
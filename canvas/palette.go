@@ -0,0 +1,105 @@
+package canvas
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// MaxPaletteSize - индексы палитры передаются одним байтом, 0xFF зарезервирован под пустую клетку
+const MaxPaletteSize = 255
+
+var ErrInvalidColor = errors.New("invalid color")
+
+// Palette - набор разрешённых цветов холста. Цвета хранятся в каноническом виде "#RRGGBB"
+type Palette struct {
+	hex   []string
+	rgba  []color.RGBA
+	index map[string]int
+}
+
+func NewPalette(hexColors []string) (*Palette, error) {
+	if len(hexColors) == 0 {
+		return nil, errors.New("palette is empty")
+	}
+	if len(hexColors) > MaxPaletteSize {
+		return nil, fmt.Errorf("palette has %d colors, at most %d are allowed", len(hexColors), MaxPaletteSize)
+	}
+
+	p := &Palette{index: make(map[string]int, len(hexColors))}
+	for _, h := range hexColors {
+		c, err := ParseHexColor(h)
+		if err != nil {
+			return nil, fmt.Errorf("palette color %q: %w", h, err)
+		}
+		canonical := FormatHexColor(c)
+		if _, dup := p.index[canonical]; dup {
+			return nil, fmt.Errorf("palette color %q is duplicated", h)
+		}
+		p.index[canonical] = len(p.hex)
+		p.hex = append(p.hex, canonical)
+		p.rgba = append(p.rgba, c)
+	}
+	return p, nil
+}
+
+// ParseHexColor разбирает цвет вида "#RRGGBB" (регистр не важен)
+func ParseHexColor(s string) (color.RGBA, error) {
+	if len(s) != 7 || s[0] != '#' {
+		return color.RGBA{}, ErrInvalidColor
+	}
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return color.RGBA{}, ErrInvalidColor
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xFF}, nil
+}
+
+func FormatHexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B)
+}
+
+func (p *Palette) Len() int {
+	return len(p.hex)
+}
+
+// Colors возвращает копию списка цветов в порядке индексов
+func (p *Palette) Colors() []string {
+	return append([]string(nil), p.hex...)
+}
+
+// Index возвращает индекс цвета в палитре
+func (p *Palette) Index(hex string) (int, bool) {
+	i, ok := p.index[strings.ToUpper(hex)]
+	return i, ok
+}
+
+// Hex возвращает цвет по индексу
+func (p *Palette) Hex(i int) (string, bool) {
+	if i < 0 || i >= len(p.hex) {
+		return "", false
+	}
+	return p.hex[i], true
+}
+
+func (p *Palette) RGBA(i int) color.RGBA {
+	return p.rgba[i]
+}
+
+// Nearest возвращает индекс ближайшего цвета палитры (евклидово расстояние в RGB)
+func (p *Palette) Nearest(c color.Color) int {
+	r, g, b, _ := c.RGBA()
+	best, bestDist := 0, -1
+	for i, pc := range p.rgba {
+		dr := int(r>>8) - int(pc.R)
+		dg := int(g>>8) - int(pc.G)
+		db := int(b>>8) - int(pc.B)
+		dist := dr*dr + dg*dg + db*db
+		if bestDist < 0 || dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
@@ -0,0 +1,38 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+)
+
+// Background - цвет пустых клеток на отрендеренных изображениях
+var Background = color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+
+// Render рисует область холста region, увеличивая каждую клетку до scale x scale
+// пикселей (nearest neighbor). Область должна лежать внутри холста
+func Render(board *Board, palette *Palette, region image.Rectangle, scale int) *image.RGBA {
+	cells, _ := board.Cells()
+	img := image.NewRGBA(image.Rect(0, 0, region.Dx()*scale, region.Dy()*scale))
+
+	for cy := region.Min.Y; cy < region.Max.Y; cy++ {
+		for cx := region.Min.X; cx < region.Max.X; cx++ {
+			c := Background
+			if index := cells[cy*board.width+cx]; index != Empty && int(index) < palette.Len() {
+				c = palette.RGBA(int(index))
+			}
+
+			ox := (cx - region.Min.X) * scale
+			oy := (cy - region.Min.Y) * scale
+			for dy := 0; dy < scale; dy++ {
+				row := img.Pix[(oy+dy)*img.Stride+ox*4:]
+				for dx := 0; dx < scale; dx++ {
+					row[dx*4] = c.R
+					row[dx*4+1] = c.G
+					row[dx*4+2] = c.B
+					row[dx*4+3] = c.A
+				}
+			}
+		}
+	}
+	return img
+}
@@ -0,0 +1,71 @@
+// Переводит сохранённые цвета пикселей на ближайшие цвета текущей палитры.
+//
+//	go run ./cmd/migrate_palette [-dry-run]
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"your_project/canvas"
+	"your_project/config"
+	"your_project/repositories"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only print the planned color mapping")
+	flag.Parse()
+
+	cfg := config.LoadConfig()
+
+	palette, err := canvas.NewPalette(cfg.Palette)
+	if err != nil {
+		log.Fatal("Invalid palette:", err)
+	}
+
+	mongoClient, err := config.InitMongoDB(cfg.MongoURI, cfg.MongoUser, cfg.MongoPassword)
+	if err != nil {
+		log.Fatal("Failed to connect to MongoDB:", err)
+	}
+	defer mongoClient.Disconnect(context.Background())
+
+	ctx := context.Background()
+	pixelRepo := repositories.NewPixelRepository(mongoClient.Database(cfg.DatabaseName))
+
+	colors, err := pixelRepo.DistinctColors(ctx)
+	if err != nil {
+		log.Fatal("Failed to list stored colors:", err)
+	}
+
+	for _, stored := range colors {
+		var target string
+		if index, ok := palette.Index(stored); ok {
+			// Цвет уже в палитре, но мог быть сохранён в другом регистре
+			target, _ = palette.Hex(index)
+		} else {
+			c, err := canvas.ParseHexColor(stored)
+			if err != nil {
+				log.Printf("Skipping unparseable color %q", stored)
+				continue
+			}
+			target, _ = palette.Hex(palette.Nearest(c))
+		}
+		if target == stored {
+			continue
+		}
+
+		if *dryRun {
+			log.Printf("%s -> %s", stored, target)
+			continue
+		}
+
+		pixels, events, err := pixelRepo.ReplaceColor(ctx, stored, target)
+		if err != nil {
+			log.Fatalf("Failed to replace %s: %v", stored, err)
+		}
+		log.Printf("%s -> %s: %d pixels, %d history events", stored, target, pixels, events)
+	}
+
+	log.Println("Palette migration finished")
+}
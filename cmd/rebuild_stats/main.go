@@ -0,0 +1,39 @@
+// Пересчитывает счётчики установок и таблицы лидеров по истории и текущему
+// холсту. Нужен, если счётчики разошлись с данными (сбой записи после установки).
+// Можно запускать при работающем сервере; установки, пришедшие в момент
+// пересчёта, могут не попасть в счётчики.
+//
+//	go run ./cmd/rebuild_stats
+package main
+
+import (
+	"context"
+	"log"
+
+	"your_project/config"
+	"your_project/repositories"
+	"your_project/services"
+)
+
+func main() {
+	cfg := config.LoadConfig()
+
+	mongoClient, err := config.InitMongoDB(cfg.MongoURI, cfg.MongoUser, cfg.MongoPassword)
+	if err != nil {
+		log.Fatal("Failed to connect to MongoDB:", err)
+	}
+	defer mongoClient.Disconnect(context.Background())
+
+	ctx := context.Background()
+	db := mongoClient.Database(cfg.DatabaseName)
+	statsRepo := repositories.NewStatsRepository(db)
+	if err := statsRepo.EnsureIndexes(ctx); err != nil {
+		log.Fatal("Failed to create stats indexes:", err)
+	}
+
+	statsService := services.NewStatsService(statsRepo, repositories.NewTeamRepository(db))
+	if err := statsService.Rebuild(ctx); err != nil {
+		log.Fatal("Failed to rebuild placement stats:", err)
+	}
+	log.Println("Placement stats rebuilt")
+}
@@ -0,0 +1,154 @@
+// Package codec - бинарный формат фреймов WebSocket для установки и рассылки
+// пикселей. Используется сервером и ботом. Клиент включает его, предлагая
+// подпротокол Subprotocol; служебные сообщения (snapshot, resume, error, pong)
+// при этом по-прежнему приходят текстовыми JSON-фреймами.
+//
+// Все числа - big endian. Первый байт фрейма - его тип:
+//
+//	FramePlace    клиент -> сервер  [type][id u32][x u16][y u16][color u8]
+//	FrameUpdates  сервер -> клиент  [type][firstSeq u64][count u16] + count * [x u16][y u16][color u8]
+//	FrameAck      сервер -> клиент  [type][id u32]
+//	FrameCooldown сервер -> клиент  [type][id u32][remainingMs u32]
+//
+// color - индекс палитры; 0xFF означает пустую клетку. Записи во FrameUpdates
+// идут подряд: у i-й записи номер обновления firstSeq+i.
+package codec
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+const Subprotocol = "pixel.bin.v1"
+
+const (
+	FramePlace    byte = 0x01
+	FrameUpdates  byte = 0x02
+	FrameAck      byte = 0x03
+	FrameCooldown byte = 0x04
+)
+
+const (
+	recordSize        = 5
+	updatesHeaderSize = 1 + 8 + 2
+
+	// MaxRecords - максимальное число записей в одном FrameUpdates
+	MaxRecords = 0xFFFF
+)
+
+var ErrMalformedFrame = errors.New("malformed frame")
+
+// Record - один пиксель на проводе
+type Record struct {
+	X     uint16
+	Y     uint16
+	Color uint8
+}
+
+// FrameType возвращает тип фрейма
+func FrameType(frame []byte) (byte, error) {
+	if len(frame) == 0 {
+		return 0, ErrMalformedFrame
+	}
+	return frame[0], nil
+}
+
+func putRecord(b []byte, r Record) {
+	binary.BigEndian.PutUint16(b[0:], r.X)
+	binary.BigEndian.PutUint16(b[2:], r.Y)
+	b[4] = r.Color
+}
+
+func readRecord(b []byte) Record {
+	return Record{
+		X:     binary.BigEndian.Uint16(b[0:]),
+		Y:     binary.BigEndian.Uint16(b[2:]),
+		Color: b[4],
+	}
+}
+
+func EncodePlace(id uint32, r Record) []byte {
+	frame := make([]byte, 1+4+recordSize)
+	frame[0] = FramePlace
+	binary.BigEndian.PutUint32(frame[1:], id)
+	putRecord(frame[5:], r)
+	return frame
+}
+
+func DecodePlace(frame []byte) (uint32, Record, error) {
+	if len(frame) != 1+4+recordSize || frame[0] != FramePlace {
+		return 0, Record{}, ErrMalformedFrame
+	}
+	return binary.BigEndian.Uint32(frame[1:]), readRecord(frame[5:]), nil
+}
+
+// EncodeUpdates упаковывает не более MaxRecords записей
+func EncodeUpdates(firstSeq uint64, records []Record) []byte {
+	if len(records) > MaxRecords {
+		records = records[:MaxRecords]
+	}
+	frame := make([]byte, updatesHeaderSize+len(records)*recordSize)
+	frame[0] = FrameUpdates
+	binary.BigEndian.PutUint64(frame[1:], firstSeq)
+	binary.BigEndian.PutUint16(frame[9:], uint16(len(records)))
+	for i, r := range records {
+		putRecord(frame[updatesHeaderSize+i*recordSize:], r)
+	}
+	return frame
+}
+
+func DecodeUpdates(frame []byte) (uint64, []Record, error) {
+	if len(frame) < updatesHeaderSize || frame[0] != FrameUpdates {
+		return 0, nil, ErrMalformedFrame
+	}
+	firstSeq := binary.BigEndian.Uint64(frame[1:])
+	count := int(binary.BigEndian.Uint16(frame[9:]))
+	if len(frame) != updatesHeaderSize+count*recordSize {
+		return 0, nil, ErrMalformedFrame
+	}
+
+	records := make([]Record, count)
+	for i := range records {
+		records[i] = readRecord(frame[updatesHeaderSize+i*recordSize:])
+	}
+	return firstSeq, records, nil
+}
+
+func EncodeAck(id uint32) []byte {
+	frame := make([]byte, 1+4)
+	frame[0] = FrameAck
+	binary.BigEndian.PutUint32(frame[1:], id)
+	return frame
+}
+
+func DecodeAck(frame []byte) (uint32, error) {
+	if len(frame) != 1+4 || frame[0] != FrameAck {
+		return 0, ErrMalformedFrame
+	}
+	return binary.BigEndian.Uint32(frame[1:]), nil
+}
+
+func EncodeCooldown(id uint32, remaining time.Duration) []byte {
+	ms := remaining.Milliseconds()
+	if ms < 0 {
+		ms = 0
+	}
+	if ms > 0xFFFFFFFF {
+		ms = 0xFFFFFFFF
+	}
+	frame := make([]byte, 1+4+4)
+	frame[0] = FrameCooldown
+	binary.BigEndian.PutUint32(frame[1:], id)
+	binary.BigEndian.PutUint32(frame[5:], uint32(ms))
+	return frame
+}
+
+func DecodeCooldown(frame []byte) (uint32, time.Duration, error) {
+	if len(frame) != 1+4+4 || frame[0] != FrameCooldown {
+		return 0, 0, ErrMalformedFrame
+	}
+	id := binary.BigEndian.Uint32(frame[1:])
+	remaining := time.Duration(binary.BigEndian.Uint32(frame[5:])) * time.Millisecond
+	return id, remaining, nil
+}
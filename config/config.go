@@ -4,6 +4,9 @@ import (
 	"context"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -15,6 +18,39 @@ type Config struct {
 	DatabaseName  string
 	MongoUser     string
 	MongoPassword string
+
+	PlacementCooldown     time.Duration // Пауза между пикселями одного кошелька
+	TeamPlacementCooldown time.Duration // Пауза между пикселями одной команды (0 - без ограничения)
+
+	TerritoryPenalty    time.Duration // Прибавка к паузе перед установкой на чужой территории с policy "penalty"
+	TerritoryMaxPerTeam int           // Сколько заявок (ждущих и действующих) может быть у команды
+	TerritoryMaxArea    int           // Максимальная площадь одной территории в клетках
+
+	LeaderboardInterval time.Duration // Как часто получателям рассылается таблица лидеров (0 - не рассылать)
+
+	CanvasWidth  int
+	CanvasHeight int
+	Palette      []string // Разрешённые цвета "#RRGGBB", порядок задаёт индексы
+
+	AuthDomain   string        // Домен, который кошелёк видит в сообщении для входа
+	ChallengeTTL time.Duration // Сколько действует сообщение для входа
+	SessionTTL   time.Duration // Срок жизни сессии и её refresh-токенов
+
+	AccessTokenTTL time.Duration // Срок жизни JWT; продлевается через /api/refresh
+
+	AdminPublicKeys []string // Кошельки, которые при старте получают роль admin, если роли ещё нет
+
+	JWTAlgorithm   string   // HS256, EdDSA или RS256
+	JWTKeys        []string // Ключи вида "kid:value", см. пакет token
+	JWTActiveKeyID string   // kid ключа для подписи новых токенов (по умолчанию первый)
+}
+
+// defaultPalette - 32 цвета по умолчанию
+var defaultPalette = []string{
+	"#6D001A", "#BE0039", "#FF4500", "#FFA800", "#FFD635", "#FFF8B8", "#00A368", "#00CC78",
+	"#7EED56", "#00756F", "#009EAA", "#00CCC0", "#2450A4", "#3690EA", "#51E9F4", "#493AC1",
+	"#6A5CFF", "#94B3FF", "#811E9F", "#B44AC0", "#E4ABFF", "#DE107F", "#FF3881", "#FF99AA",
+	"#6D482F", "#9C6926", "#FFB470", "#000000", "#515252", "#898D90", "#D4D7D9", "#FFFFFF",
 }
 
 func LoadConfig() *Config {
@@ -24,9 +60,46 @@ func LoadConfig() *Config {
 		DatabaseName:  getEnv("DATABASE_NAME", "pixelcanvas"),
 		MongoUser:     getEnv("MONGO_USER", "admin"),
 		MongoPassword: getEnv("MONGO_PASSWORD", "password"),
+
+		PlacementCooldown:     getEnvDuration("PLACEMENT_COOLDOWN", 5*time.Second),
+		TeamPlacementCooldown: getEnvDuration("TEAM_PLACEMENT_COOLDOWN", 0),
+
+		TerritoryPenalty:    getEnvDuration("TERRITORY_PENALTY", 30*time.Second),
+		TerritoryMaxPerTeam: getEnvInt("TERRITORY_MAX_PER_TEAM", 3),
+		TerritoryMaxArea:    getEnvInt("TERRITORY_MAX_AREA", 64*64),
+
+		LeaderboardInterval: getEnvDuration("LEADERBOARD_INTERVAL", 10*time.Second),
+
+		CanvasWidth:  getEnvInt("CANVAS_WIDTH", 500),
+		CanvasHeight: getEnvInt("CANVAS_HEIGHT", 300),
+		Palette:      getEnvList("PALETTE", defaultPalette),
+
+		AuthDomain:   getEnv("AUTH_DOMAIN", "localhost:3000"),
+		ChallengeTTL: getEnvDuration("CHALLENGE_TTL", 5*time.Minute),
+		SessionTTL:   getEnvDuration("SESSION_TTL", 72*time.Hour),
+
+		AccessTokenTTL: getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+
+		AdminPublicKeys: getEnvList("ADMIN_PUBLIC_KEYS", nil),
+
+		JWTAlgorithm:   getEnv("JWT_ALGORITHM", "HS256"),
+		JWTKeys:        getJWTKeys(),
+		JWTActiveKeyID: getEnv("JWT_ACTIVE_KID", ""),
 	}
 }
 
+// getJWTKeys читает JWT_KEYS; для совместимости принимает и одиночный JWT_SECRET
+func getJWTKeys() []string {
+	if keys := getEnvList("JWT_KEYS", nil); len(keys) > 0 {
+		return keys
+	}
+	if secret, exists := os.LookupEnv("JWT_SECRET"); exists && secret != "" {
+		return []string{"default:" + secret}
+	}
+	log.Println("WARNING: JWT_KEYS is not set, using the insecure development secret")
+	return []string{"default:your_jwt_secret_key"}
+}
+
 func getEnv(key, defaultVal string) string {
 	if val, exists := os.LookupEnv(key); exists {
 		return val
@@ -34,6 +107,46 @@ func getEnv(key, defaultVal string) string {
 	return defaultVal
 }
 
+func getEnvList(key string, defaultVal []string) []string {
+	val, exists := os.LookupEnv(key)
+	if !exists || strings.TrimSpace(val) == "" {
+		return defaultVal
+	}
+	var list []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getEnvInt(key string, defaultVal int) int {
+	val, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultVal
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		log.Printf("Invalid integer in %s=%q, using %d", key, val, defaultVal)
+		return defaultVal
+	}
+	return n
+}
+
+func getEnvDuration(key string, defaultVal time.Duration) time.Duration {
+	val, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultVal
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		log.Printf("Invalid duration in %s=%q, using %s", key, val, defaultVal)
+		return defaultVal
+	}
+	return d
+}
+
 func InitMongoDB(uri, username, password string) (*mongo.Client, error) {
 	clientOptions := options.Client().ApplyURI(uri).SetAuth(options.Credential{
 		Username: username,
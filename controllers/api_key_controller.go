@@ -0,0 +1,130 @@
+// controllers/api_key_controller.go
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"your_project/middlewares"
+	"your_project/services"
+)
+
+// KeyDisconnector закрывает WebSocket-соединения ботов с отозванным ключом (websocket.Hub)
+type KeyDisconnector interface {
+	DisconnectAPIKey(publicKey, apiKeyID string)
+}
+
+type APIKeyController struct {
+	APIKeyService services.APIKeyService
+	Sockets       KeyDisconnector
+}
+
+func NewAPIKeyController(apiKeyService services.APIKeyService, sockets KeyDisconnector) *APIKeyController {
+	return &APIKeyController{
+		APIKeyService: apiKeyService,
+		Sockets:       sockets,
+	}
+}
+
+// KeysHandler: GET - ключи текущего кошелька, POST - выпустить новый.
+// Ключ в открытом виде возвращается только в ответе на POST
+func (kc *APIKeyController) KeysHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	publicKey, ok := r.Context().Value(middlewares.ContextKeyPublicKey).(string)
+	if !ok || publicKey == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		keys, err := kc.APIKeyService.GetKeys(r.Context(), publicKey)
+		if err != nil {
+			http.Error(w, "Failed to get API keys", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": keys,
+		})
+
+	case http.MethodPost:
+		var req struct {
+			Name   string   `json:"name"`
+			Scopes []string `json:"scopes"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		key, raw, err := kc.APIKeyService.CreateKey(r.Context(), publicKey, req.Name, req.Scopes)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrInvalidKeyName), errors.Is(err, services.ErrInvalidScope):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, services.ErrTooManyAPIKeys):
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				http.Error(w, "Failed to create API key", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"key":    raw,
+			"apiKey": key,
+		})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// RevokeKeyHandler отзывает ключ текущего кошелька и отключает его ботов
+func (kc *APIKeyController) RevokeKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	publicKey, ok := r.Context().Value(middlewares.ContextKeyPublicKey).(string)
+	if !ok || publicKey == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := kc.APIKeyService.RevokeKey(r.Context(), publicKey, req.ID); err != nil {
+		if errors.Is(err, services.ErrAPIKeyNotFound) {
+			http.Error(w, "API key not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to revoke API key", http.StatusInternalServerError)
+		}
+		return
+	}
+	kc.Sockets.DisconnectAPIKey(publicKey, req.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
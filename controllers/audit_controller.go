@@ -0,0 +1,161 @@
+// controllers/audit_controller.go
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"your_project/middlewares"
+	"your_project/models"
+	"your_project/services"
+)
+
+type AuditController struct {
+	AuditService services.AuditService
+}
+
+func NewAuditController(auditService services.AuditService) *AuditController {
+	return &AuditController{
+		AuditService: auditService,
+	}
+}
+
+// GetAuditHandler - страница журнала аудита
+// (?actor=&action=&targetType=&targetId=&from=&to=&limit=&offset=)
+func (ac *AuditController) GetAuditHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	query, err := auditQuery(params)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if query.Limit, err = intParam(params.Get("limit"), 0); err != nil {
+		http.Error(w, "Invalid limit", http.StatusBadRequest)
+		return
+	}
+	if query.Offset, err = intParam(params.Get("offset"), 0); err != nil {
+		http.Error(w, "Invalid offset", http.StatusBadRequest)
+		return
+	}
+
+	page, err := ac.AuditService.GetEntries(r.Context(), query)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAuditQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Failed to get audit log", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// ExportAuditHandler отдаёт весь журнал (с теми же фильтрами) в JSONL:
+// по записи на строку, от старых к новым
+func (ac *AuditController) ExportAuditHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query, err := auditQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Заголовки уходят с первой записью: до неё ещё можно ответить ошибкой
+	encoder := json.NewEncoder(w)
+	started := false
+	err = ac.AuditService.Export(r.Context(), query, func(entry *models.AuditEntry) error {
+		if !started {
+			ac.startExport(w)
+			started = true
+		}
+		return encoder.Encode(entry)
+	})
+	if err != nil {
+		if started {
+			log.Println("Audit export interrupted:", err)
+			return
+		}
+		if errors.Is(err, services.ErrInvalidAuditQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Failed to export audit log", http.StatusInternalServerError)
+		}
+		return
+	}
+	if !started {
+		ac.startExport(w)
+	}
+}
+
+func (ac *AuditController) startExport(w http.ResponseWriter) {
+	filename := "audit-" + time.Now().UTC().Format("20060102-150405") + ".jsonl"
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+}
+
+// auditQuery разбирает фильтры журнала; limit и offset разбирает вызывающий
+func auditQuery(params url.Values) (models.AuditQuery, error) {
+	query := models.AuditQuery{
+		Actor:      params.Get("actor"),
+		Action:     models.AuditAction(params.Get("action")),
+		TargetType: models.AuditTargetType(params.Get("targetType")),
+		TargetID:   params.Get("targetId"),
+	}
+	var err error
+	if query.From, err = timeParam(params.Get("from")); err != nil {
+		return query, errors.New("invalid from: expected RFC 3339 time")
+	}
+	if query.To, err = timeParam(params.Get("to")); err != nil {
+		return query, errors.New("invalid to: expected RFC 3339 time")
+	}
+	return query, nil
+}
+
+// recordAudit записывает действие текущего пользователя в журнал. Действие
+// к этому моменту уже выполнено, поэтому сбой записи только логируется
+func recordAudit(auditService services.AuditService, r *http.Request, action models.AuditAction, target models.AuditTarget, before, after interface{}) {
+	actor, _ := r.Context().Value(middlewares.ContextKeyPublicKey).(string)
+	role, _ := r.Context().Value(middlewares.ContextKeyRole).(models.Role)
+
+	entry := &models.AuditEntry{
+		Actor:     actor,
+		ActorRole: role,
+		Action:    action,
+		Target:    target,
+		Before:    before,
+		After:     after,
+		Request: models.AuditRequest{
+			Method:    r.Method,
+			Path:      r.URL.Path,
+			IPAddress: clientIP(r),
+			UserAgent: r.UserAgent(),
+		},
+	}
+	if err := auditService.Record(r.Context(), entry); err != nil {
+		log.Printf("Failed to record audit entry %s by %s: %v", action, actor, err)
+	}
+}
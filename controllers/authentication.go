@@ -1,30 +1,46 @@
 package controllers
 
 import (
-	"crypto/ed25519"
 	"encoding/base64"
 	"encoding/json"
-	"fmt"
-	"github.com/golang-jwt/jwt"
+	"errors"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"time"
 	"your_project/middlewares"
-
-	"github.com/blocto/solana-go-sdk/common"
+	"your_project/models"
+	"your_project/services"
+	"your_project/token"
 )
 
-var jwtSecret = []byte("your_jwt_secret_key")
-
 type AuthRequest struct {
 	PublicKey string `json:"publicKey"`
 	Signature string `json:"signature"` // Base64-encoded signature
-	Message   string `json:"message"`
+	Message   string `json:"message"`   // Сообщение из /api/get-challenge, без изменений
+}
+
+// Disconnector закрывает WebSocket-соединения отозванных сессий (websocket.Hub)
+type Disconnector interface {
+	DisconnectWallet(publicKey string)
+	DisconnectSession(publicKey, sessionID string)
 }
 
-var nonceStore = make(map[string]string)
+type AuthController struct {
+	AuthService services.AuthService
+	Tokens      *token.Manager
+	Sockets     Disconnector
+}
 
-func AuthenticateHandler(w http.ResponseWriter, r *http.Request) {
+func NewAuthController(authService services.AuthService, tokens *token.Manager, sockets Disconnector) *AuthController {
+	return &AuthController{
+		AuthService: authService,
+		Tokens:      tokens,
+		Sockets:     sockets,
+	}
+}
+
+func (ac *AuthController) AuthenticateHandler(w http.ResponseWriter, r *http.Request) {
 	var authReq AuthRequest
 
 	body, err := ioutil.ReadAll(r.Body)
@@ -45,56 +61,122 @@ func AuthenticateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Verify the signature
-	isValid, err := verifySignature(authReq.PublicKey, signatureBytes, authReq.Message)
+	// Проверяем, что подписано выданное нами сообщение с живым nonce, и открываем сессию
+	tokens, err := ac.AuthService.SignIn(r.Context(), services.SignInRequest{
+		PublicKey: authReq.PublicKey,
+		Message:   authReq.Message,
+		Signature: signatureBytes,
+		UserAgent: r.UserAgent(),
+		IPAddress: clientIP(r),
+	})
 	if err != nil {
-		http.Error(w, "Error verifying signature", http.StatusInternalServerError)
+		switch {
+		case errors.Is(err, services.ErrInvalidSignature):
+			http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		case errors.Is(err, services.ErrChallengeNotFound), errors.Is(err, services.ErrChallengeExpired):
+			http.Error(w, "Challenge expired or already used", http.StatusUnauthorized)
+		case errors.Is(err, services.ErrMessageMismatch):
+			http.Error(w, "Signed message does not match the challenge", http.StatusUnauthorized)
+		default:
+			http.Error(w, "Error verifying signature", http.StatusInternalServerError)
+		}
 		return
 	}
 
-	if !isValid {
-		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+	expiresAt, err := ac.setSessionCookies(w, tokens)
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 		return
 	}
 
-	// Создание JWT-токена
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"publicKey": authReq.PublicKey,
-		"exp":       time.Now().Add(time.Hour * 72).Unix(), // Токен действителен 72 часа
+	// Ответ клиенту
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"expiresAt": expiresAt,
 	})
+}
+
+// RefreshHandler обменивает refresh-токен из куки на новую пару токенов
+func (ac *AuthController) RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cookie, err := r.Cookie(refreshCookieName)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
 
-	tokenString, err := token.SignedString(jwtSecret)
+	tokens, err := ac.AuthService.Refresh(r.Context(), cookie.Value)
+	if err != nil {
+		var reuseErr *services.TokenReuseError
+		switch {
+		case errors.As(err, &reuseErr):
+			// Токен утёк: сессия уже отозвана, закрываем и её соединения
+			ac.Sockets.DisconnectSession(reuseErr.PublicKey, reuseErr.SessionID)
+			clearSessionCookies(w)
+			http.Error(w, "Refresh token reused", http.StatusUnauthorized)
+		case errors.Is(err, services.ErrInvalidRefreshToken):
+			clearSessionCookies(w)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		default:
+			http.Error(w, "Failed to refresh token", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	expiresAt, err := ac.setSessionCookies(w, tokens)
 	if err != nil {
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"expiresAt": expiresAt,
+	})
+}
+
+// refreshCookieName - куки с refresh-токеном. Путь /api: её получают только
+// /api/refresh и /api/logout, но не статика и не WebSocket
+const refreshCookieName = "refresh_token"
+
+// setSessionCookies выпускает access-токен и выставляет обе куки.
+// Возвращает время истечения access-токена
+func (ac *AuthController) setSessionCookies(w http.ResponseWriter, tokens *services.SessionTokens) (time.Time, error) {
+	session := tokens.Session
+	tokenString, expiresAt, err := ac.Tokens.Issue(session.PublicKey, session.ID, tokens.Role, session.ExpiresAt)
+	if err != nil {
+		return time.Time{}, err
+	}
+
 	http.SetCookie(w, &http.Cookie{
 		Name:     "token",
 		Value:    tokenString,
-		Expires:  time.Now().Add(time.Hour * 72),
+		Expires:  expiresAt,
 		HttpOnly: true,
 		Path:     "/",
 		SameSite: http.SameSiteLaxMode, // или http.SameSiteStrictMode
 	})
-
-	// Ответ клиенту
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshCookieName,
+		Value:    tokens.RefreshToken,
+		Expires:  session.ExpiresAt,
+		HttpOnly: true,
+		Path:     "/api",
+		SameSite: http.SameSiteStrictMode,
 	})
+	return expiresAt, nil
 }
 
-func verifySignature(pubKeyStr string, signature []byte, message string) (bool, error) {
-	// Convert public key string to bytes
-	publicKey := common.PublicKeyFromString(pubKeyStr).Bytes()
-
-	// Verify the signature using ed25519.Verify
-	isValid := ed25519.Verify(publicKey, []byte(message), signature)
-
-	return isValid, nil
-}
-
-func GetChallengeHandler(w http.ResponseWriter, r *http.Request) {
+func (ac *AuthController) GetChallengeHandler(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		PublicKey string `json:"publicKey"`
 	}
@@ -110,22 +192,25 @@ func GetChallengeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate a random nonce
-	nonce := generateNonce()
-
-	// Store the nonce associated with the public key
-	nonceStore[req.PublicKey] = nonce
+	// Сообщение для подписи формирует сервер; клиент подписывает его без изменений
+	challenge, err := ac.AuthService.IssueChallenge(r.Context(), req.PublicKey)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidPublicKey) {
+			http.Error(w, "Invalid public key", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Failed to issue challenge", http.StatusInternalServerError)
+		}
+		return
+	}
 
 	response := map[string]interface{}{
-		"nonce": nonce,
+		"nonce":     challenge.Nonce,
+		"message":   challenge.Message,
+		"expiresAt": challenge.ExpiresAt,
 	}
 	json.NewEncoder(w).Encode(response)
 }
 
-func generateNonce() string {
-	return fmt.Sprintf("%d", time.Now().UnixNano())
-}
-
 func MeHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusOK)
@@ -138,12 +223,19 @@ func MeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	apiKeyID, _ := r.Context().Value(middlewares.ContextKeyAPIKeyID).(string)
+	role, _ := r.Context().Value(middlewares.ContextKeyRole).(models.Role)
+
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"publicKey": publicKey,
+		"bot":       apiKeyID != "",
+		"role":      role,
 	})
 }
 
-func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+// LogoutHandler отзывает токен из куки вместе с его сессией и удаляет куки.
+// Куки удаляются, даже если токены уже недействительны
+func (ac *AuthController) LogoutHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusOK)
 		return
@@ -153,7 +245,92 @@ func LogoutHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Удаляем куки, установив их срок действия в прошлое
+	if err := ac.endSession(r); err != nil {
+		http.Error(w, "Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+
+	clearSessionCookies(w)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+// LogoutAllHandler - выход со всех устройств: отзывает все сессии кошелька
+// и закрывает его WebSocket-соединения
+func (ac *AuthController) LogoutAllHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	publicKey, ok := r.Context().Value(middlewares.ContextKeyPublicKey).(string)
+	if !ok || publicKey == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	revoked, err := ac.AuthService.RevokeAllSessions(r.Context(), publicKey)
+	if err != nil {
+		http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+		return
+	}
+	ac.Sockets.DisconnectWallet(publicKey)
+
+	clearSessionCookies(w)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"revoked": revoked,
+	})
+}
+
+// endSession закрывает сессию, из которой пришёл запрос. Если access-токен
+// уже истёк, сессия находится по refresh-токену
+func (ac *AuthController) endSession(r *http.Request) error {
+	if cookie, err := r.Cookie("token"); err == nil {
+		if claims, err := ac.Tokens.Parse(cookie.Value); err == nil {
+			err := ac.AuthService.Logout(r.Context(), services.RevokeTokenRequest{
+				TokenID:   claims.Id,
+				SessionID: claims.SessionID,
+				PublicKey: claims.PublicKey,
+				ExpiresAt: claims.Expiry(),
+			})
+			if err != nil {
+				return err
+			}
+			ac.Sockets.DisconnectSession(claims.PublicKey, claims.SessionID)
+			return nil
+		}
+	}
+
+	cookie, err := r.Cookie(refreshCookieName)
+	if err != nil {
+		return nil
+	}
+	session, err := ac.AuthService.SessionByRefreshToken(r.Context(), cookie.Value)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) {
+			return nil
+		}
+		return err
+	}
+	err = ac.AuthService.RevokeSession(r.Context(), session.PublicKey, session.ID)
+	if err != nil && !errors.Is(err, services.ErrSessionNotFound) {
+		return err
+	}
+	ac.Sockets.DisconnectSession(session.PublicKey, session.ID)
+	return nil
+}
+
+// clearSessionCookies удаляет куки, установив их срок действия в прошлое
+func clearSessionCookies(w http.ResponseWriter) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     "token",
 		Value:    "",
@@ -163,8 +340,112 @@ func LogoutHandler(w http.ResponseWriter, r *http.Request) {
 		HttpOnly: true,
 		SameSite: http.SameSiteLaxMode, // Установите по необходимости
 	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshCookieName,
+		Value:    "",
+		Path:     "/api",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// GetSessionsHandler - активные сессии текущего кошелька
+func (ac *AuthController) GetSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	publicKey, ok := r.Context().Value(middlewares.ContextKeyPublicKey).(string)
+	if !ok || publicKey == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	currentID, _ := r.Context().Value(middlewares.ContextKeySessionID).(string)
+
+	sessions, err := ac.AuthService.GetSessions(r.Context(), publicKey)
+	if err != nil {
+		http.Error(w, "Failed to get sessions", http.StatusInternalServerError)
+		return
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"success": true}`))
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"sessions": sessions,
+	})
+}
+
+// RevokeSessionHandler отзывает одну из сессий текущего кошелька
+func (ac *AuthController) RevokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	publicKey, ok := r.Context().Value(middlewares.ContextKeyPublicKey).(string)
+	if !ok || publicKey == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		SessionID string `json:"sessionId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := ac.AuthService.RevokeSession(r.Context(), publicKey, req.SessionID); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			http.Error(w, "Session not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+		}
+		return
+	}
+	ac.Sockets.DisconnectSession(publicKey, req.SessionID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
+
+// clientIP возвращает адрес клиента без порта
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// GetJWKSHandler публикует открытые ключи для проверки токенов
+func (ac *AuthController) GetJWKSHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(ac.Tokens.JWKS())
 }
@@ -0,0 +1,176 @@
+// controllers/ban_controller.go
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"your_project/middlewares"
+	"your_project/models"
+	"your_project/services"
+)
+
+type BanController struct {
+	BanService   services.BanService
+	AuditService services.AuditService
+	Sockets      Disconnector
+}
+
+func NewBanController(banService services.BanService, auditService services.AuditService, sockets Disconnector) *BanController {
+	return &BanController{
+		BanService:   banService,
+		AuditService: auditService,
+		Sockets:      sockets,
+	}
+}
+
+// BansHandler: GET - список банов (?publicKey=&active=true&limit=&offset=),
+// POST - забанить кошелёк. Доступен модераторам (RequireRole)
+func (bc *BanController) BansHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		bc.listBans(w, r)
+	case http.MethodPost:
+		bc.createBan(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (bc *BanController) listBans(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := models.BanQuery{
+		PublicKey:  params.Get("publicKey"),
+		ActiveOnly: params.Get("active") == "true",
+	}
+	var err error
+	if query.Limit, err = intParam(params.Get("limit"), 0); err != nil {
+		http.Error(w, "Invalid limit", http.StatusBadRequest)
+		return
+	}
+	if query.Offset, err = intParam(params.Get("offset"), 0); err != nil {
+		http.Error(w, "Invalid offset", http.StatusBadRequest)
+		return
+	}
+
+	bans, err := bc.BanService.GetBans(r.Context(), query)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidBanList) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Failed to get bans", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"bans": bans,
+	})
+}
+
+func (bc *BanController) createBan(w http.ResponseWriter, r *http.Request) {
+	actor, _ := r.Context().Value(middlewares.ContextKeyPublicKey).(string)
+
+	var req struct {
+		PublicKey string         `json:"publicKey"`
+		Type      models.BanType `json:"type"`
+		Reason    string         `json:"reason"`
+		Duration  string         `json:"duration"` // Например "24h"; пусто - бессрочно
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	var duration time.Duration
+	if req.Duration != "" {
+		var err error
+		if duration, err = time.ParseDuration(req.Duration); err != nil || duration <= 0 {
+			http.Error(w, "Invalid duration", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ban, err := bc.BanService.Ban(r.Context(), services.BanRequest{
+		PublicKey: req.PublicKey,
+		Type:      req.Type,
+		Reason:    req.Reason,
+		Duration:  duration,
+		IssuedBy:  actor,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidBan), errors.Is(err, services.ErrInvalidPublicKey):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrCannotBanPeer):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, "Failed to ban wallet", http.StatusInternalServerError)
+		}
+		return
+	}
+	recordAudit(bc.AuditService, r, models.AuditBanCreate,
+		models.AuditTarget{Type: models.AuditTargetWallet, ID: ban.PublicKey}, nil, ban)
+
+	// Полный бан выкидывает кошелёк и из открытых WebSocket
+	if ban.Type == models.BanFull {
+		bc.Sockets.DisconnectWallet(ban.PublicKey)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ban)
+}
+
+// LiftBanHandler снимает бан /api/admin/bans/{id}/lift
+func (bc *BanController) LiftBanHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	actor, _ := r.Context().Value(middlewares.ContextKeyPublicKey).(string)
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	// Причина снятия необязательна, тело может быть пустым
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ban, err := bc.BanService.LiftBan(r.Context(), r.PathValue("id"), actor, req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrBanNotFound):
+			http.Error(w, "Ban not found or already lifted", http.StatusNotFound)
+		case errors.Is(err, services.ErrCannotLiftBan):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, "Failed to lift ban", http.StatusInternalServerError)
+		}
+		return
+	}
+	// Снятие меняет только поля lift*, остальное до и после одинаково
+	before := *ban
+	before.LiftedBy, before.LiftedAt, before.LiftReason = "", nil, ""
+	recordAudit(bc.AuditService, r, models.AuditBanLift,
+		models.AuditTarget{Type: models.AuditTargetWallet, ID: ban.PublicKey}, before, ban)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ban)
+}
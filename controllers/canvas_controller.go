@@ -0,0 +1,152 @@
+// controllers/canvas_controller.go
+package controllers
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"your_project/canvas"
+)
+
+const (
+	maxPNGScale = 32
+	maxPNGSide  = 4096 // Ограничение на сторону итоговой картинки в пикселях
+)
+
+type CanvasController struct {
+	Board   *canvas.Board
+	Palette *canvas.Palette
+	// Epoch - эпоха хаба: после рестарта версии холста начинаются заново,
+	// поэтому ETag без неё может совпасть с закешированным до рестарта
+	Epoch string
+}
+
+func NewCanvasController(board *canvas.Board, palette *canvas.Palette, epoch string) *CanvasController {
+	return &CanvasController{
+		Board:   board,
+		Palette: palette,
+		Epoch:   epoch,
+	}
+}
+
+// GetSnapshotHandler отдаёт холст одним блобом: width*height байт построчно,
+// каждый байт - индекс палитры или 0xFF для пустой клетки. Тело сжато gzip,
+// если клиент его принимает
+func (cc *CanvasController) GetSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	gzipped := acceptsGzip(r)
+	var body []byte
+	var version uint64
+	if gzipped {
+		var err error
+		if body, version, err = cc.Board.Snapshot(); err != nil {
+			http.Error(w, "Failed to build snapshot", http.StatusInternalServerError)
+			return
+		}
+	} else {
+		body, version = cc.Board.Cells()
+	}
+
+	// У сжатого и несжатого тела разные ETag: это разные представления
+	etag := fmt.Sprintf(`"%s-%d"`, cc.Epoch, version)
+	if gzipped {
+		etag = fmt.Sprintf(`"%s-%d-gzip"`, cc.Epoch, version)
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Vary", "Accept-Encoding")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Canvas-Width", strconv.Itoa(cc.Board.Width()))
+	w.Header().Set("X-Canvas-Height", strconv.Itoa(cc.Board.Height()))
+	w.Header().Set("X-Canvas-Version", strconv.FormatUint(version, 10))
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if gzipped {
+		w.Header().Set("Content-Encoding", "gzip")
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.Write(body)
+}
+
+// acceptsGzip проверяет Accept-Encoding; "gzip;q=0" означает отказ
+func acceptsGzip(r *http.Request) bool {
+	for _, header := range r.Header.Values("Accept-Encoding") {
+		for _, part := range strings.Split(header, ",") {
+			coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+			coding = strings.ToLower(strings.TrimSpace(coding))
+			if coding != "gzip" && coding != "*" {
+				continue
+			}
+			q := strings.ReplaceAll(strings.ToLower(params), " ", "")
+			if q != "q=0" && q != "q=0.0" && q != "q=0.00" && q != "q=0.000" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetPNGHandler рендерит холст или его область (x, y, w, h) в PNG с увеличением scale
+func (cc *CanvasController) GetPNGHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	x, errX := intParam(q.Get("x"), 0)
+	y, errY := intParam(q.Get("y"), 0)
+	width, errW := intParam(q.Get("w"), cc.Board.Width()-x)
+	height, errH := intParam(q.Get("h"), cc.Board.Height()-y)
+	scale, errS := intParam(q.Get("scale"), 1)
+	if errX != nil || errY != nil || errW != nil || errH != nil || errS != nil {
+		http.Error(w, "Invalid parameters", http.StatusBadRequest)
+		return
+	}
+
+	region := image.Rect(x, y, x+width, y+height)
+	bounds := image.Rect(0, 0, cc.Board.Width(), cc.Board.Height())
+	if width <= 0 || height <= 0 || !region.In(bounds) {
+		http.Error(w, "Region is outside the canvas", http.StatusBadRequest)
+		return
+	}
+	if scale < 1 || scale > maxPNGScale || width*scale > maxPNGSide || height*scale > maxPNGSide {
+		http.Error(w, "Scale is too large", http.StatusBadRequest)
+		return
+	}
+
+	version := cc.Board.Version()
+	etag := fmt.Sprintf(`"%s-%d-%d-%d-%d-%d-%d"`, cc.Epoch, version, x, y, width, height, scale)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	img := canvas.Render(cc.Board, cc.Palette, region, scale)
+	w.Header().Set("Content-Type", "image/png")
+	if err := png.Encode(w, img); err != nil {
+		http.Error(w, "Failed to encode PNG", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,197 @@
+// controllers/pixel_controller.go
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"your_project/middlewares"
+	"your_project/models"
+	"your_project/services"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type PixelController struct {
+	PixelService services.PixelService
+}
+
+func NewPixelController(pixelService services.PixelService) *PixelController {
+	return &PixelController{
+		PixelService: pixelService,
+	}
+}
+
+// GetPixelHandler возвращает текущего владельца пикселя (для подсказок при наведении)
+func (pc *PixelController) GetPixelHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	x, errX := strconv.Atoi(r.PathValue("x"))
+	y, errY := strconv.Atoi(r.PathValue("y"))
+	if errX != nil || errY != nil {
+		http.Error(w, "Invalid coordinates", http.StatusBadRequest)
+		return
+	}
+
+	pixel, err := pc.PixelService.GetPixel(r.Context(), x, y)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			http.Error(w, "Pixel not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to get pixel", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pixel)
+}
+
+// GetPixelHistoryHandler - история установок для клетки (x, y) или прямоугольника (x, y, w, h)
+func (pc *PixelController) GetPixelHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	query := models.PixelHistoryQuery{Width: 1, Height: 1}
+	var err error
+	if query.X, err = strconv.Atoi(q.Get("x")); err != nil {
+		http.Error(w, "Invalid x parameter", http.StatusBadRequest)
+		return
+	}
+	if query.Y, err = strconv.Atoi(q.Get("y")); err != nil {
+		http.Error(w, "Invalid y parameter", http.StatusBadRequest)
+		return
+	}
+	if query.Width, err = intParam(q.Get("w"), 1); err != nil {
+		http.Error(w, "Invalid w parameter", http.StatusBadRequest)
+		return
+	}
+	if query.Height, err = intParam(q.Get("h"), 1); err != nil {
+		http.Error(w, "Invalid h parameter", http.StatusBadRequest)
+		return
+	}
+	if query.From, err = timeParam(q.Get("from")); err != nil {
+		http.Error(w, "Invalid from parameter", http.StatusBadRequest)
+		return
+	}
+	if query.To, err = timeParam(q.Get("to")); err != nil {
+		http.Error(w, "Invalid to parameter", http.StatusBadRequest)
+		return
+	}
+	if query.Limit, err = intParam(q.Get("limit"), 0); err != nil {
+		http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
+		return
+	}
+	if query.Offset, err = intParam(q.Get("offset"), 0); err != nil {
+		http.Error(w, "Invalid offset parameter", http.StatusBadRequest)
+		return
+	}
+
+	page, err := pc.PixelService.GetPixelHistory(r.Context(), query)
+	if err != nil {
+		if err == services.ErrInvalidHistoryQuery {
+			http.Error(w, "Invalid history query", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Failed to get pixel history", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// intParam разбирает необязательный целочисленный параметр запроса
+func intParam(value string, defaultVal int) (int, error) {
+	if value == "" {
+		return defaultVal, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// timeParam разбирает необязательный параметр времени в формате RFC 3339
+func timeParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// GetCooldownHandler - сколько осталось ждать текущему кошельку до следующего пикселя
+func (pc *PixelController) GetCooldownHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	publicKey, ok := r.Context().Value(middlewares.ContextKeyPublicKey).(string)
+	if !ok || publicKey == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	remaining, err := pc.PixelService.CooldownRemaining(r.Context(), publicKey)
+	if err != nil {
+		http.Error(w, "Failed to get cooldown", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"cooldownMs":  pc.PixelService.Cooldown().Milliseconds(),
+		"remainingMs": remaining.Milliseconds(),
+		"readyAt":     time.Now().UTC().Add(remaining),
+	})
+}
+
+// GetCanvasHandler - размеры холста и другие метаданные
+func (pc *PixelController) GetCanvasHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pc.PixelService.Canvas())
+}
+
+// GetPaletteHandler - цвета палитры в порядке индексов
+func (pc *PixelController) GetPaletteHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"colors": pc.PixelService.Palette().Colors(),
+	})
+}
@@ -0,0 +1,257 @@
+// controllers/report_controller.go
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"your_project/middlewares"
+	"your_project/models"
+	"your_project/services"
+)
+
+type ReportController struct {
+	ReportService services.ReportService
+	AuditService  services.AuditService
+}
+
+func NewReportController(reportService services.ReportService, auditService services.AuditService) *ReportController {
+	return &ReportController{
+		ReportService: reportService,
+		AuditService:  auditService,
+	}
+}
+
+// CreateReportHandler - жалоба игрока на область холста или кошелёк
+func (rc *ReportController) CreateReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	reporter, _ := r.Context().Value(middlewares.ContextKeyPublicKey).(string)
+
+	var req struct {
+		Type      models.ReportType `json:"type"`
+		PublicKey string            `json:"publicKey"`
+		Region    *models.Region    `json:"region"`
+		Reason    string            `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	report, err := rc.ReportService.CreateReport(r.Context(), services.ReportRequest{
+		Reporter:  reporter,
+		Type:      req.Type,
+		PublicKey: req.PublicKey,
+		Region:    req.Region,
+		Reason:    req.Reason,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidReport):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrTooManyReports):
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+		default:
+			http.Error(w, "Failed to create report", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(report)
+}
+
+// ReportsHandler - очередь модерации (?status=open&assignedTo=&limit=&offset=),
+// от старых жалоб к новым
+func (rc *ReportController) ReportsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	query := models.ReportQuery{
+		Status:     models.ReportStatus(params.Get("status")),
+		AssignedTo: params.Get("assignedTo"),
+	}
+	var err error
+	if query.Limit, err = intParam(params.Get("limit"), 0); err != nil {
+		http.Error(w, "Invalid limit", http.StatusBadRequest)
+		return
+	}
+	if query.Offset, err = intParam(params.Get("offset"), 0); err != nil {
+		http.Error(w, "Invalid offset", http.StatusBadRequest)
+		return
+	}
+
+	reports, err := rc.ReportService.GetReports(r.Context(), query)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidReportList) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Failed to get reports", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"reports": reports,
+	})
+}
+
+// GetReportHandler - жалоба и установки, видимые в области на момент жалобы
+func (rc *ReportController) GetReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report, placements, err := rc.ReportService.GetReport(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeReportError(w, err, "Failed to get report")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"report":     report,
+		"placements": placements,
+	})
+}
+
+// GetThumbnailHandler отдаёт PNG области, снятый при создании жалобы
+func (rc *ReportController) GetThumbnailHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	thumbnail, err := rc.ReportService.GetThumbnail(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeReportError(w, err, "Failed to get report")
+		return
+	}
+	if len(thumbnail) == 0 {
+		http.Error(w, "Report has no thumbnail", http.StatusNotFound)
+		return
+	}
+
+	// Миниатюра не меняется, её можно кэшировать
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Length", strconv.Itoa(len(thumbnail)))
+	w.Write(thumbnail)
+}
+
+// AssignReportHandler назначает жалобу модератору; без assignee - себе
+func (rc *ReportController) AssignReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	actor, _ := r.Context().Value(middlewares.ContextKeyPublicKey).(string)
+
+	var req struct {
+		Assignee string `json:"assignee"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Assignee == "" {
+		req.Assignee = actor
+	}
+
+	before, report, err := rc.ReportService.Assign(r.Context(), r.PathValue("id"), req.Assignee)
+	if err != nil {
+		writeReportError(w, err, "Failed to assign report")
+		return
+	}
+	recordAudit(rc.AuditService, r, models.AuditReportAssign,
+		models.AuditTarget{Type: models.AuditTargetReport, ID: report.ID.Hex()},
+		map[string]interface{}{"assignedTo": before.AssignedTo},
+		map[string]interface{}{"assignedTo": report.AssignedTo})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// ResolveReportHandler закрывает жалобу: {status: actioned|dismissed, resolution}
+func (rc *ReportController) ResolveReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	actor, _ := r.Context().Value(middlewares.ContextKeyPublicKey).(string)
+
+	var req struct {
+		Status     models.ReportStatus `json:"status"`
+		Resolution string              `json:"resolution"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	before, report, err := rc.ReportService.Resolve(r.Context(), r.PathValue("id"), req.Status, actor, req.Resolution)
+	if err != nil {
+		writeReportError(w, err, "Failed to resolve report")
+		return
+	}
+	recordAudit(rc.AuditService, r, models.AuditReportResolve,
+		models.AuditTarget{Type: models.AuditTargetReport, ID: report.ID.Hex()},
+		map[string]interface{}{"status": before.Status},
+		map[string]interface{}{"status": report.Status, "resolution": report.Resolution})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+func writeReportError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrReportNotFound):
+		http.Error(w, "Report not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrReportClosed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, services.ErrInvalidAssignee), errors.Is(err, services.ErrInvalidResolution):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,86 @@
+// controllers/role_controller.go
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"your_project/middlewares"
+	"your_project/models"
+	"your_project/services"
+)
+
+type RoleController struct {
+	RoleService  services.RoleService
+	AuditService services.AuditService
+}
+
+func NewRoleController(roleService services.RoleService, auditService services.AuditService) *RoleController {
+	return &RoleController{
+		RoleService:  roleService,
+		AuditService: auditService,
+	}
+}
+
+// RolesHandler: GET - модераторы и админы, POST - назначить роль кошельку.
+// Доступен только админам (RequireRole)
+func (rc *RoleController) RolesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		roles, err := rc.RoleService.GetPrivileged(r.Context())
+		if err != nil {
+			http.Error(w, "Failed to get roles", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"roles": roles,
+		})
+
+	case http.MethodPost:
+		actor, _ := r.Context().Value(middlewares.ContextKeyPublicKey).(string)
+
+		var req struct {
+			PublicKey string      `json:"publicKey"`
+			Role      models.Role `json:"role"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		// Прежняя роль нужна для журнала аудита
+		previous, err := rc.RoleService.RoleOf(r.Context(), req.PublicKey)
+		if err != nil {
+			http.Error(w, "Failed to set role", http.StatusInternalServerError)
+			return
+		}
+
+		role, err := rc.RoleService.SetRole(r.Context(), actor, req.PublicKey, req.Role)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrInvalidRole), errors.Is(err, services.ErrInvalidPublicKey),
+				errors.Is(err, services.ErrCannotChangeOwnRole):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			default:
+				http.Error(w, "Failed to set role", http.StatusInternalServerError)
+			}
+			return
+		}
+		recordAudit(rc.AuditService, r, models.AuditRoleSet,
+			models.AuditTarget{Type: models.AuditTargetWallet, ID: role.PublicKey},
+			map[string]interface{}{"role": previous}, role)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(role)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
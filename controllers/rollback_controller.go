@@ -0,0 +1,157 @@
+// controllers/rollback_controller.go
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"your_project/middlewares"
+	"your_project/models"
+	"your_project/services"
+)
+
+// PixelPublisher рассылает изменённые пиксели получателям (websocket.Hub)
+type PixelPublisher interface {
+	PublishPixels(pixels []models.Pixel)
+}
+
+type RollbackController struct {
+	RollbackService services.RollbackService
+	AuditService    services.AuditService
+	Hub             PixelPublisher
+}
+
+func NewRollbackController(rollbackService services.RollbackService, auditService services.AuditService, hub PixelPublisher) *RollbackController {
+	return &RollbackController{
+		RollbackService: rollbackService,
+		AuditService:    auditService,
+		Hub:             hub,
+	}
+}
+
+// RestoreRegionHandler возвращает прямоугольник к состоянию на момент "at".
+// С dryRun только считает, сколько клеток изменится
+func (rc *RollbackController) RestoreRegionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	actor, _ := r.Context().Value(middlewares.ContextKeyPublicKey).(string)
+
+	var req struct {
+		X      int       `json:"x"`
+		Y      int       `json:"y"`
+		Width  int       `json:"width"`
+		Height int       `json:"height"`
+		At     time.Time `json:"at"`
+		DryRun bool      `json:"dryRun"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := rc.RollbackService.RestoreRegion(r.Context(), services.RestoreRegionRequest{
+		X:      req.X,
+		Y:      req.Y,
+		Width:  req.Width,
+		Height: req.Height,
+		At:     req.At,
+		Actor:  actor,
+		DryRun: req.DryRun,
+	})
+	rc.respond(w, r, result, err, models.AuditRollbackRegion, models.AuditTarget{
+		Type:   models.AuditTargetRegion,
+		Region: &models.Region{X: req.X, Y: req.Y, Width: req.Width, Height: req.Height},
+	}, map[string]interface{}{"at": req.At})
+}
+
+// RevertWalletHandler отменяет все установки кошелька за [from, to)
+func (rc *RollbackController) RevertWalletHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	actor, _ := r.Context().Value(middlewares.ContextKeyPublicKey).(string)
+
+	var req struct {
+		PublicKey string    `json:"publicKey"`
+		From      time.Time `json:"from"`
+		To        time.Time `json:"to"`
+		DryRun    bool      `json:"dryRun"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := rc.RollbackService.RevertWallet(r.Context(), services.RevertWalletRequest{
+		PublicKey: req.PublicKey,
+		From:      req.From,
+		To:        req.To,
+		Actor:     actor,
+		DryRun:    req.DryRun,
+	})
+	rc.respond(w, r, result, err, models.AuditRollbackWallet, models.AuditTarget{
+		Type: models.AuditTargetWallet,
+		ID:   req.PublicKey,
+	}, map[string]interface{}{"from": req.From, "to": req.To})
+}
+
+// respond применяет результат отката: рассылает пиксели и пишет в аудит клетки до и после.
+// params - параметры отката для журнала
+func (rc *RollbackController) respond(w http.ResponseWriter, r *http.Request, result *models.RollbackResult, err error,
+	action models.AuditAction, target models.AuditTarget, params map[string]interface{}) {
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidRollback):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrRollbackTooLarge):
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		default:
+			http.Error(w, "Failed to roll back", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if !result.DryRun {
+		rc.Hub.PublishPixels(result.Changed)
+		params["changed"] = result.Count
+		params["pixels"] = auditPixels(result.Changed)
+		recordAudit(rc.AuditService, r, action, target,
+			map[string]interface{}{"pixels": auditPixels(result.Replaced)}, params)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// auditPixel - клетка в журнале аудита. Без команды и времени установки:
+// у отката на предельное число клеток полные пиксели до и после
+// не поместятся в один документ MongoDB
+type auditPixel struct {
+	X         int    `json:"x" bson:"x"`
+	Y         int    `json:"y" bson:"y"`
+	Color     string `json:"color" bson:"color"`
+	PublicKey string `json:"publicKey,omitempty" bson:"publicKey,omitempty"`
+}
+
+func auditPixels(pixels []models.Pixel) []auditPixel {
+	compact := make([]auditPixel, len(pixels))
+	for i, pixel := range pixels {
+		compact[i] = auditPixel{X: pixel.X, Y: pixel.Y, Color: pixel.Color, PublicKey: pixel.PublicKey}
+	}
+	return compact
+}
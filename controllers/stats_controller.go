@@ -0,0 +1,97 @@
+// controllers/stats_controller.go
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"your_project/models"
+	"your_project/services"
+)
+
+type StatsController struct {
+	StatsService services.StatsService
+}
+
+func NewStatsController(statsService services.StatsService) *StatsController {
+	return &StatsController{
+		StatsService: statsService,
+	}
+}
+
+// GetTeamLeaderboardHandler - таблица лидеров команд (?sort=total|owned|hour|day&limit=)
+func (sc *StatsController) GetTeamLeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query, err := leaderboardQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, "Invalid limit", http.StatusBadRequest)
+		return
+	}
+	teams, err := sc.StatsService.GetTeamLeaderboard(r.Context(), query)
+	if err != nil {
+		writeLeaderboardError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"teams": teams,
+	})
+}
+
+// GetPlayerLeaderboardHandler - таблица лидеров кошельков (?sort=total|owned|hour|day&limit=)
+func (sc *StatsController) GetPlayerLeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query, err := leaderboardQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, "Invalid limit", http.StatusBadRequest)
+		return
+	}
+	players, err := sc.StatsService.GetPlayerLeaderboard(r.Context(), query)
+	if err != nil {
+		writeLeaderboardError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"players": players,
+	})
+}
+
+func leaderboardQuery(params url.Values) (models.LeaderboardQuery, error) {
+	limit, err := intParam(params.Get("limit"), 0)
+	if err != nil {
+		return models.LeaderboardQuery{}, err
+	}
+	return models.LeaderboardQuery{
+		Sort:  models.LeaderboardSort(params.Get("sort")),
+		Limit: limit,
+	}, nil
+}
+
+func writeLeaderboardError(w http.ResponseWriter, err error) {
+	if errors.Is(err, services.ErrInvalidLeaderboard) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, "Failed to get leaderboard", http.StatusInternalServerError)
+}
@@ -3,6 +3,7 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"your_project/middlewares"
 	"your_project/models"
@@ -44,7 +45,12 @@ func (tc *TeamController) CreateTeamHandler(w http.ResponseWriter, r *http.Reque
 	ctx := r.Context()
 	team, err := tc.TeamService.CreateTeam(ctx, req.Name, req.PublicKey)
 	if err != nil {
-		http.Error(w, "Failed to create team", http.StatusInternalServerError)
+		var banErr *services.BanError
+		if errors.As(err, &banErr) {
+			http.Error(w, banErr.Error(), http.StatusForbidden)
+		} else {
+			http.Error(w, "Failed to create team", http.StatusInternalServerError)
+		}
 		return
 	}
 	err = tc.TeamService.JoinTeam(ctx, req.Name, req.PublicKey)
@@ -121,8 +127,11 @@ func (tc *TeamController) JoinTeamHandler(w http.ResponseWriter, r *http.Request
 	// Добавляем пользователя в команду
 	err = tc.TeamService.JoinTeam(ctx, req.TeamID, publicKey)
 	if err != nil {
+		var banErr *services.BanError
 		if err == mongo.ErrNoDocuments {
 			http.Error(w, "Team not found", http.StatusNotFound)
+		} else if errors.As(err, &banErr) {
+			http.Error(w, banErr.Error(), http.StatusForbidden)
 		} else {
 			http.Error(w, "Failed to join team", http.StatusInternalServerError)
 		}
@@ -0,0 +1,189 @@
+// controllers/territory_controller.go
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"your_project/middlewares"
+	"your_project/models"
+	"your_project/services"
+)
+
+// CanvasPublisher сообщает клиентам об изменении метаданных холста (websocket.Hub)
+type CanvasPublisher interface {
+	BroadcastCanvas()
+}
+
+type TerritoryController struct {
+	TerritoryService services.TerritoryService
+	AuditService     services.AuditService
+	Hub              CanvasPublisher
+}
+
+func NewTerritoryController(territoryService services.TerritoryService, auditService services.AuditService, hub CanvasPublisher) *TerritoryController {
+	return &TerritoryController{
+		TerritoryService: territoryService,
+		AuditService:     auditService,
+		Hub:              hub,
+	}
+}
+
+// GetTerritoriesHandler - заявки на территории (?teamId=&status=pending|approved|rejected|released)
+func (tc *TerritoryController) GetTerritoriesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	territories, err := tc.TerritoryService.GetTerritories(r.Context(), models.TerritoryQuery{
+		TeamID: params.Get("teamId"),
+		Status: models.TerritoryStatus(params.Get("status")),
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTerritory) {
+			http.Error(w, "Invalid status", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Failed to get territories", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"territories": territories,
+	})
+}
+
+// ClaimTerritoryHandler подаёт от команды пользователя заявку на прямоугольник:
+// {region: {x, y, width, height}, policy: restricted|penalty}
+func (tc *TerritoryController) ClaimTerritoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	publicKey, _ := r.Context().Value(middlewares.ContextKeyPublicKey).(string)
+
+	var req struct {
+		Region models.Region          `json:"region"`
+		Policy models.TerritoryPolicy `json:"policy"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	territory, err := tc.TerritoryService.Claim(r.Context(), services.ClaimRequest{
+		PublicKey: publicKey,
+		Region:    req.Region,
+		Policy:    req.Policy,
+	})
+	if err != nil {
+		writeTerritoryError(w, err, "Failed to claim territory")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(territory)
+}
+
+// ReleaseTerritoryHandler снимает заявку или территорию своей команды
+func (tc *TerritoryController) ReleaseTerritoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	publicKey, _ := r.Context().Value(middlewares.ContextKeyPublicKey).(string)
+
+	territory, err := tc.TerritoryService.Release(r.Context(), r.PathValue("id"), publicKey)
+	if err != nil {
+		writeTerritoryError(w, err, "Failed to release territory")
+		return
+	}
+	tc.Hub.BroadcastCanvas()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(territory)
+}
+
+// ApproveTerritoryHandler одобряет заявку: с этого момента территория действует
+func (tc *TerritoryController) ApproveTerritoryHandler(w http.ResponseWriter, r *http.Request) {
+	tc.review(w, r, models.AuditTerritoryApprove, tc.TerritoryService.Approve)
+}
+
+// RejectTerritoryHandler отклоняет заявку или отзывает действующую территорию
+func (tc *TerritoryController) RejectTerritoryHandler(w http.ResponseWriter, r *http.Request) {
+	tc.review(w, r, models.AuditTerritoryReject, tc.TerritoryService.Reject)
+}
+
+// review - общее для решений админа по заявке /api/admin/territories/{id}/...
+// с необязательным телом {note}
+func (tc *TerritoryController) review(w http.ResponseWriter, r *http.Request, action models.AuditAction,
+	decide func(ctx context.Context, id, reviewer, note string) (*models.Territory, *models.Territory, error)) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	reviewer, _ := r.Context().Value(middlewares.ContextKeyPublicKey).(string)
+
+	var req struct {
+		Note string `json:"note"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	before, territory, err := decide(r.Context(), r.PathValue("id"), reviewer, req.Note)
+	if err != nil {
+		writeTerritoryError(w, err, "Failed to review territory")
+		return
+	}
+	recordAudit(tc.AuditService, r, action,
+		models.AuditTarget{Type: models.AuditTargetTeam, ID: territory.TeamID}, before, territory)
+	tc.Hub.BroadcastCanvas()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(territory)
+}
+
+func writeTerritoryError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidTerritory), errors.Is(err, services.ErrNotInTeam):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrTerritoryNotFound):
+		http.Error(w, "Territory not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrTerritoryOverlap), errors.Is(err, services.ErrTooManyTerritories),
+		errors.Is(err, services.ErrTerritoryNotPending):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.As(err, new(*services.BanError)):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
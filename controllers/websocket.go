@@ -2,20 +2,55 @@ package controllers
 
 import (
 	"net/http"
+	"strconv"
 
+	"your_project/middlewares"
 	"your_project/websocket"
 )
 
+// HandleWebSocket - единый WebSocket: рассылка обновлений и установка пикселей.
+// Без токена и API-ключа клиент только смотрит, с неверными получает 401
+func HandleWebSocket(hub *websocket.Hub, auth *middlewares.Authenticator, w http.ResponseWriter, r *http.Request) {
+	identity, err := auth.AuthenticateWebSocket(r)
+	if err != nil && err != middlewares.ErrNoToken {
+		middlewares.WriteAuthError(w, err)
+		return
+	}
+
+	conn, err := websocket.Upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		hub.Logger.Println("WebSocket upgrade error:", err)
+		return
+	}
+
+	websocket.NewClient(conn, hub, websocket.ClientOptions{
+		Identity:   identity,
+		Auth:       auth,
+		Receive:    true,
+		ResumeFrom: resumePoint(r),
+	})
+}
+
 // HandleSendWebSocket - WebSocket для отправки пикселей
-func HandleSendWebSocket(hub *websocket.Hub, w http.ResponseWriter, r *http.Request) {
+func HandleSendWebSocket(hub *websocket.Hub, auth *middlewares.Authenticator, w http.ResponseWriter, r *http.Request) {
+	// Рисовать могут только авторизованные кошельки
+	identity, err := auth.AuthenticateWebSocket(r)
+	if err != nil {
+		middlewares.WriteAuthError(w, err)
+		return
+	}
+
 	conn, err := websocket.Upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		hub.Logger.Println("WebSocket send upgrade error:", err)
 		return
 	}
 
-	client := websocket.NewSendClient(conn, hub) // Новый клиент для отправки
-	hub.RegisterSendClient(client)
+	websocket.NewClient(conn, hub, websocket.ClientOptions{
+		Identity: identity,
+		Auth:     auth,
+		Legacy:   true,
+	}) // Новый клиент для отправки
 }
 
 // HandleReceiveWebSocket - WebSocket для получения обновлений
@@ -26,6 +61,21 @@ func HandleReceiveWebSocket(hub *websocket.Hub, w http.ResponseWriter, r *http.R
 		return
 	}
 
-	client := websocket.NewReceiveClient(conn, hub) // Новый клиент для получения
-	hub.RegisterReceiveClient(client)
+	websocket.NewClient(conn, hub, websocket.ClientOptions{
+		Receive:    true,
+		Legacy:     true,
+		ResumeFrom: resumePoint(r),
+	}) // Новый клиент для получения
+}
+
+// resumePoint разбирает ?epoch=...&since=<seq>: переподключившийся клиент получит только пропущенное
+func resumePoint(r *http.Request) *websocket.ResumePoint {
+	seq, err := strconv.ParseUint(r.URL.Query().Get("since"), 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &websocket.ResumePoint{
+		Epoch: r.URL.Query().Get("epoch"),
+		Seq:   seq,
+	}
 }
@@ -0,0 +1,182 @@
+// controllers/zone_controller.go
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"your_project/middlewares"
+	"your_project/models"
+	"your_project/services"
+)
+
+type ZoneController struct {
+	ZoneService  services.ZoneService
+	AuditService services.AuditService
+}
+
+func NewZoneController(zoneService services.ZoneService, auditService services.AuditService) *ZoneController {
+	return &ZoneController{
+		ZoneService:  zoneService,
+		AuditService: auditService,
+	}
+}
+
+// zoneRequest - тело создания и изменения зоны
+type zoneRequest struct {
+	Name         string           `json:"name"`
+	Shape        models.ZoneShape `json:"shape"`
+	Region       *models.Region   `json:"region"`
+	Polygon      []models.Cell    `json:"polygon"`
+	StartsAt     *time.Time       `json:"startsAt"`
+	EndsAt       *time.Time       `json:"endsAt"`
+	AllowedTeams []string         `json:"allowedTeams"`
+}
+
+func (req zoneRequest) toService(actor string) services.ZoneRequest {
+	return services.ZoneRequest{
+		Name:         req.Name,
+		Shape:        req.Shape,
+		Region:       req.Region,
+		Polygon:      req.Polygon,
+		StartsAt:     req.StartsAt,
+		EndsAt:       req.EndsAt,
+		AllowedTeams: req.AllowedTeams,
+		Actor:        actor,
+	}
+}
+
+// GetZonesHandler - действующие и будущие защищённые зоны, чтобы фронтенд мог их затенить
+func (zc *ZoneController) GetZonesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	zones := zc.ZoneService.GetZones(false)
+	// Кто из админов завёл зону, игрокам знать незачем
+	for i := range zones {
+		zones[i].CreatedBy = ""
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"zones": zones,
+	})
+}
+
+// ZonesHandler: GET - все зоны, включая закончившиеся, POST - создать зону.
+// Доступен только админам (RequireRole)
+func (zc *ZoneController) ZonesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"zones": zc.ZoneService.GetZones(true),
+		})
+
+	case http.MethodPost:
+		actor, _ := r.Context().Value(middlewares.ContextKeyPublicKey).(string)
+
+		var req zoneRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		zone, err := zc.ZoneService.CreateZone(r.Context(), req.toService(actor))
+		if err != nil {
+			writeZoneError(w, err, "Failed to create zone")
+			return
+		}
+		recordAudit(zc.AuditService, r, models.AuditZoneCreate,
+			models.AuditTarget{Type: models.AuditTargetZone, ID: zone.ID.Hex()}, nil, zone)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(zone)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// UpdateZoneHandler заменяет зону /api/admin/zones/{id} целиком
+func (zc *ZoneController) UpdateZoneHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	actor, _ := r.Context().Value(middlewares.ContextKeyPublicKey).(string)
+
+	var req zoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	before, zone, err := zc.ZoneService.UpdateZone(r.Context(), r.PathValue("id"), req.toService(actor))
+	if err != nil {
+		writeZoneError(w, err, "Failed to update zone")
+		return
+	}
+	recordAudit(zc.AuditService, r, models.AuditZoneUpdate,
+		models.AuditTarget{Type: models.AuditTargetZone, ID: zone.ID.Hex()}, before, zone)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(zone)
+}
+
+// DeleteZoneHandler удаляет зону /api/admin/zones/{id}/delete
+func (zc *ZoneController) DeleteZoneHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	zone, err := zc.ZoneService.DeleteZone(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeZoneError(w, err, "Failed to delete zone")
+		return
+	}
+	recordAudit(zc.AuditService, r, models.AuditZoneDelete,
+		models.AuditTarget{Type: models.AuditTargetZone, ID: zone.ID.Hex()}, zone, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
+
+func writeZoneError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidZone):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrZoneNotFound):
+		http.Error(w, "Zone not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrTooManyZones):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
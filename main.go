@@ -4,13 +4,17 @@ package main
 import (
 	"context"
 	"log"
+	"math"
 	"net/http"
 	"your_project/repositories"
 	"your_project/services"
 
+	"your_project/canvas"
 	"your_project/config"
 	"your_project/controllers"
 	"your_project/middlewares"
+	"your_project/models"
+	"your_project/token"
 	"your_project/websocket"
 )
 
@@ -29,18 +33,115 @@ func main() {
 		}
 	}()
 
+	tokens, err := token.NewManager(cfg)
+	if err != nil {
+		log.Fatal("Invalid JWT configuration:", err)
+	}
+
+	palette, err := canvas.NewPalette(cfg.Palette)
+	if err != nil {
+		log.Fatal("Invalid palette:", err)
+	}
+
+	// Координаты в бинарных кадрах - uint16, больший холст не передать клиентам
+	if cfg.CanvasWidth < 1 || cfg.CanvasWidth > math.MaxUint16 || cfg.CanvasHeight < 1 || cfg.CanvasHeight > math.MaxUint16 {
+		log.Fatalf("Invalid canvas size %dx%d: width and height must be between 1 and %d", cfg.CanvasWidth, cfg.CanvasHeight, math.MaxUint16)
+	}
+
 	db := mongoClient.Database(cfg.DatabaseName)
+	auditRepo := repositories.NewAuditRepository(db)
+	if err := auditRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatal("Failed to create audit indexes:", err)
+	}
+	auditService := services.NewAuditService(auditRepo)
+	auditController := controllers.NewAuditController(auditService)
+	roleRepo := repositories.NewRoleRepository(db)
+	roleService := services.NewRoleService(roleRepo)
+	if err := roleService.BootstrapAdmins(context.Background(), cfg.AdminPublicKeys); err != nil {
+		log.Fatal("Failed to bootstrap admins:", err)
+	}
+	roleController := controllers.NewRoleController(roleService, auditService)
+	banRepo := repositories.NewBanRepository(db)
+	if err := banRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatal("Failed to create ban indexes:", err)
+	}
+	banService := services.NewBanService(banRepo, roleService)
 	teamRepo := repositories.NewTeamRepository(db)
-	teamService := services.NewTeamService(teamRepo)
+	teamService := services.NewTeamService(teamRepo, banService)
 	teamController := controllers.NewTeamController(teamService)
+	sessionRepo := repositories.NewSessionRepository(db)
+	if err := sessionRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatal("Failed to create session indexes:", err)
+	}
+	authService := services.NewAuthService(sessionRepo, roleService, cfg)
+	apiKeyRepo := repositories.NewAPIKeyRepository(db)
+	if err := apiKeyRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatal("Failed to create API key indexes:", err)
+	}
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo)
+	auth := middlewares.NewAuthenticator(authService, tokens, apiKeyService, banService)
+	pixelRepo := repositories.NewPixelRepository(db)
+	if err := pixelRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatal("Failed to create pixel indexes:", err)
+	}
+	statsRepo := repositories.NewStatsRepository(db)
+	if err := statsRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatal("Failed to create stats indexes:", err)
+	}
+	statsService := services.NewStatsService(statsRepo, teamRepo)
+	if err := statsService.Backfill(context.Background()); err != nil {
+		log.Fatal("Failed to backfill placement stats:", err)
+	}
+	statsController := controllers.NewStatsController(statsService)
+	zoneRepo := repositories.NewZoneRepository(db)
+	zoneService := services.NewZoneService(zoneRepo, cfg)
+	if err := zoneService.Load(context.Background()); err != nil {
+		log.Fatal("Failed to load protected zones:", err)
+	}
+	zoneController := controllers.NewZoneController(zoneService, auditService)
+	territoryRepo := repositories.NewTerritoryRepository(db)
+	if err := territoryRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatal("Failed to create territory indexes:", err)
+	}
+	territoryService := services.NewTerritoryService(territoryRepo, teamRepo, banService, zoneService, cfg)
+	if err := territoryService.Load(context.Background()); err != nil {
+		log.Fatal("Failed to load territories:", err)
+	}
+	pixelService := services.NewPixelService(pixelRepo, teamRepo, banService, zoneService, territoryService, statsService, cfg, palette)
+	rollbackService := services.NewRollbackService(pixelRepo, statsService, cfg, palette)
+	pixelController := controllers.NewPixelController(pixelService)
 
 	// Инициализация WebSocket хаба
-	hub := websocket.NewHub(mongoClient, cfg.DatabaseName)
+	hub := websocket.NewHub(pixelService)
+	if err := hub.LoadCanvas(context.Background()); err != nil {
+		log.Fatal("Failed to load canvas:", err)
+	}
 	go hub.Run()
+	if cfg.LeaderboardInterval > 0 {
+		go hub.RunLeaderboard(statsService, cfg.LeaderboardInterval)
+	}
+
+	authController := controllers.NewAuthController(authService, tokens, hub)
+	apiKeyController := controllers.NewAPIKeyController(apiKeyService, hub)
+	banController := controllers.NewBanController(banService, auditService, hub)
+	rollbackController := controllers.NewRollbackController(rollbackService, auditService, hub)
+	canvasController := controllers.NewCanvasController(hub.Board(), palette, hub.Epoch())
+	territoryController := controllers.NewTerritoryController(territoryService, auditService, hub)
+	reportRepo := repositories.NewReportRepository(db)
+	if err := reportRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatal("Failed to create report indexes:", err)
+	}
+	reportService := services.NewReportService(reportRepo, pixelRepo, roleService, hub.Board(), palette)
+	reportController := controllers.NewReportController(reportService, auditService)
 
 	// Установка маршрута для WebSocket
+	http.Handle("/ws", middlewares.CORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		controllers.HandleWebSocket(hub, auth, w, r)
+	})))
+
+	// Старые эндпоинты с раздельными сокетами
 	http.Handle("/ws/send", middlewares.CORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		controllers.HandleSendWebSocket(hub, w, r)
+		controllers.HandleSendWebSocket(hub, auth, w, r)
 	})))
 
 	http.Handle("/ws/receive", middlewares.CORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -48,15 +149,53 @@ func main() {
 	})))
 
 	// Добавление эндпоинтов аутентификации с использованием CORS middleware
-	http.Handle("/api/get-challenge", middlewares.CORS(http.HandlerFunc(controllers.GetChallengeHandler)))
-	http.Handle("/api/authenticate", middlewares.CORS(http.HandlerFunc(controllers.AuthenticateHandler)))
+	http.Handle("/api/get-challenge", middlewares.CORS(http.HandlerFunc(authController.GetChallengeHandler)))
+	http.Handle("/api/authenticate", middlewares.CORS(http.HandlerFunc(authController.AuthenticateHandler)))
+	http.Handle("/api/refresh", middlewares.CORS(http.HandlerFunc(authController.RefreshHandler)))
+	http.Handle("/.well-known/jwks.json", middlewares.CORS(http.HandlerFunc(authController.GetJWKSHandler)))
 	http.Handle("/api/teams", middlewares.CORS(http.HandlerFunc(teamController.GetTeamsHandler)))
 	http.Handle("/api/teams/members", middlewares.CORS(http.HandlerFunc(teamController.GetTeamMembersHandler)))
-	http.Handle("/api/teams/leave", middlewares.CORS(middlewares.JWTAuth(http.HandlerFunc(teamController.LeaveTeamHandler))))
-	http.Handle("/api/me", middlewares.CORS(middlewares.JWTAuth(http.HandlerFunc(controllers.MeHandler))))
-	http.Handle("/api/teams/create", middlewares.CORS(middlewares.JWTAuth(http.HandlerFunc(teamController.CreateTeamHandler))))
-	http.Handle("/api/teams/join", middlewares.CORS(middlewares.JWTAuth(http.HandlerFunc(teamController.JoinTeamHandler))))
-	http.Handle("/api/logout", middlewares.CORS(http.HandlerFunc(controllers.LogoutHandler)))
+	http.Handle("/api/teams/leave", middlewares.CORS(auth.APIKeyAuth(models.ScopeTeamsWrite, http.HandlerFunc(teamController.LeaveTeamHandler))))
+	http.Handle("/api/me/cooldown", middlewares.CORS(auth.APIKeyAuth("", http.HandlerFunc(pixelController.GetCooldownHandler))))
+	http.Handle("/api/me", middlewares.CORS(auth.APIKeyAuth("", http.HandlerFunc(controllers.MeHandler))))
+	http.Handle("/api/teams/create", middlewares.CORS(auth.APIKeyAuth(models.ScopeTeamsWrite, http.HandlerFunc(teamController.CreateTeamHandler))))
+	http.Handle("/api/teams/join", middlewares.CORS(auth.APIKeyAuth(models.ScopeTeamsWrite, http.HandlerFunc(teamController.JoinTeamHandler))))
+	http.Handle("/api/teams/territories/claim", middlewares.CORS(auth.APIKeyAuth(models.ScopeTeamsWrite, http.HandlerFunc(territoryController.ClaimTerritoryHandler))))
+	http.Handle("/api/teams/territories/{id}/release", middlewares.CORS(auth.APIKeyAuth(models.ScopeTeamsWrite, http.HandlerFunc(territoryController.ReleaseTerritoryHandler))))
+	http.Handle("/api/territories", middlewares.CORS(http.HandlerFunc(territoryController.GetTerritoriesHandler)))
+	http.Handle("/api/canvas", middlewares.CORS(http.HandlerFunc(pixelController.GetCanvasHandler)))
+	http.Handle("/api/canvas.png", middlewares.CORS(http.HandlerFunc(canvasController.GetPNGHandler)))
+	http.Handle("/api/canvas/snapshot", middlewares.CORS(http.HandlerFunc(canvasController.GetSnapshotHandler)))
+	http.Handle("/api/palette", middlewares.CORS(http.HandlerFunc(pixelController.GetPaletteHandler)))
+	http.Handle("/api/leaderboard/teams", middlewares.CORS(http.HandlerFunc(statsController.GetTeamLeaderboardHandler)))
+	http.Handle("/api/leaderboard/players", middlewares.CORS(http.HandlerFunc(statsController.GetPlayerLeaderboardHandler)))
+	http.Handle("/api/zones", middlewares.CORS(http.HandlerFunc(zoneController.GetZonesHandler)))
+	http.Handle("/api/pixels/history", middlewares.CORS(http.HandlerFunc(pixelController.GetPixelHistoryHandler)))
+	http.Handle("/api/pixels/{x}/{y}", middlewares.CORS(http.HandlerFunc(pixelController.GetPixelHandler)))
+	http.Handle("/api/sessions", middlewares.CORS(auth.JWTAuth(http.HandlerFunc(authController.GetSessionsHandler))))
+	http.Handle("/api/sessions/revoke", middlewares.CORS(auth.JWTAuth(http.HandlerFunc(authController.RevokeSessionHandler))))
+	http.Handle("/api/logout", middlewares.CORS(http.HandlerFunc(authController.LogoutHandler)))
+	http.Handle("/api/keys", middlewares.CORS(auth.JWTAuth(http.HandlerFunc(apiKeyController.KeysHandler))))
+	http.Handle("/api/keys/revoke", middlewares.CORS(auth.JWTAuth(http.HandlerFunc(apiKeyController.RevokeKeyHandler))))
+	http.Handle("/api/reports", middlewares.CORS(auth.JWTAuth(http.HandlerFunc(reportController.CreateReportHandler))))
+	http.Handle("/api/admin/roles", middlewares.CORS(auth.JWTAuth(middlewares.RequireRole(models.RoleAdmin, http.HandlerFunc(roleController.RolesHandler)))))
+	http.Handle("/api/admin/bans", middlewares.CORS(auth.JWTAuth(middlewares.RequireRole(models.RoleModerator, http.HandlerFunc(banController.BansHandler)))))
+	http.Handle("/api/admin/bans/{id}/lift", middlewares.CORS(auth.JWTAuth(middlewares.RequireRole(models.RoleModerator, http.HandlerFunc(banController.LiftBanHandler)))))
+	http.Handle("/api/admin/reports", middlewares.CORS(auth.JWTAuth(middlewares.RequireRole(models.RoleModerator, http.HandlerFunc(reportController.ReportsHandler)))))
+	http.Handle("/api/admin/reports/{id}", middlewares.CORS(auth.JWTAuth(middlewares.RequireRole(models.RoleModerator, http.HandlerFunc(reportController.GetReportHandler)))))
+	http.Handle("/api/admin/reports/{id}/thumbnail", middlewares.CORS(auth.JWTAuth(middlewares.RequireRole(models.RoleModerator, http.HandlerFunc(reportController.GetThumbnailHandler)))))
+	http.Handle("/api/admin/reports/{id}/assign", middlewares.CORS(auth.JWTAuth(middlewares.RequireRole(models.RoleModerator, http.HandlerFunc(reportController.AssignReportHandler)))))
+	http.Handle("/api/admin/reports/{id}/resolve", middlewares.CORS(auth.JWTAuth(middlewares.RequireRole(models.RoleModerator, http.HandlerFunc(reportController.ResolveReportHandler)))))
+	http.Handle("/api/admin/zones", middlewares.CORS(auth.JWTAuth(middlewares.RequireRole(models.RoleAdmin, http.HandlerFunc(zoneController.ZonesHandler)))))
+	http.Handle("/api/admin/zones/{id}", middlewares.CORS(auth.JWTAuth(middlewares.RequireRole(models.RoleAdmin, http.HandlerFunc(zoneController.UpdateZoneHandler)))))
+	http.Handle("/api/admin/zones/{id}/delete", middlewares.CORS(auth.JWTAuth(middlewares.RequireRole(models.RoleAdmin, http.HandlerFunc(zoneController.DeleteZoneHandler)))))
+	http.Handle("/api/admin/territories/{id}/approve", middlewares.CORS(auth.JWTAuth(middlewares.RequireRole(models.RoleAdmin, http.HandlerFunc(territoryController.ApproveTerritoryHandler)))))
+	http.Handle("/api/admin/territories/{id}/reject", middlewares.CORS(auth.JWTAuth(middlewares.RequireRole(models.RoleAdmin, http.HandlerFunc(territoryController.RejectTerritoryHandler)))))
+	http.Handle("/api/admin/audit", middlewares.CORS(auth.JWTAuth(middlewares.RequireRole(models.RoleAdmin, http.HandlerFunc(auditController.GetAuditHandler)))))
+	http.Handle("/api/admin/audit/export", middlewares.CORS(auth.JWTAuth(middlewares.RequireRole(models.RoleAdmin, http.HandlerFunc(auditController.ExportAuditHandler)))))
+	http.Handle("/api/admin/rollback/region", middlewares.CORS(auth.JWTAuth(middlewares.RequireRole(models.RoleAdmin, http.HandlerFunc(rollbackController.RestoreRegionHandler)))))
+	http.Handle("/api/admin/rollback/wallet", middlewares.CORS(auth.JWTAuth(middlewares.RequireRole(models.RoleAdmin, http.HandlerFunc(rollbackController.RevertWalletHandler)))))
+	http.Handle("/api/logout/all", middlewares.CORS(auth.JWTAuth(http.HandlerFunc(authController.LogoutAllHandler))))
 
 	// Запуск HTTP-сервера
 	log.Printf("Server is running on %s", cfg.ServerAddress)
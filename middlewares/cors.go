@@ -9,7 +9,8 @@ func CORS(next http.Handler) http.Handler {
 		w.Header().Set("Access-Control-Allow-Origin", "http://localhost:3000")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-None-Match, X-API-Key")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, X-Canvas-Width, X-Canvas-Height, X-Canvas-Version")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
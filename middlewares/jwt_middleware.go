@@ -4,54 +4,261 @@ package middlewares
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
+	"time"
 
-	"github.com/golang-jwt/jwt"
+	"your_project/models"
+	"your_project/services"
+	"your_project/token"
 )
 
 type contextKey string
 
 const (
 	ContextKeyPublicKey = contextKey("publicKey")
+	ContextKeySessionID = contextKey("sessionId")
+	ContextKeyTokenID   = contextKey("tokenId")
+	ContextKeyAPIKeyID  = contextKey("apiKeyId")
+	ContextKeyRole      = contextKey("role")
+
+	// APIKeyHeader - заголовок, в котором боты передают API-ключ
+	APIKeyHeader = "X-API-Key"
+
+	// WebSocketTokenProtocol - подпротокол, после которого клиент передаёт JWT
+	// в Sec-WebSocket-Protocol: "bearer, <token>"
+	WebSocketTokenProtocol = "bearer"
+)
+
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrNoToken      = errors.New("no token")
 )
 
-var jwtSecret = []byte("your_jwt_secret_key") // Должен совпадать с секретом из authentication.go
+// TokenValidator проверяет, что токен и его сессия не отозваны
+type TokenValidator interface {
+	ValidateToken(ctx context.Context, tokenID, sessionID, publicKey string) error
+}
+
+// APIKeyValidator находит активный API-ключ по его значению
+type APIKeyValidator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error)
+}
+
+// BanChecker возвращает *services.BanError, если кошельку запрещён вход
+type BanChecker interface {
+	CheckAccess(ctx context.Context, publicKey string) error
+}
+
+// Identity - кошелёк, сессия и токен, от имени которых сделан запрос.
+// Для API-ключа сессии и токена нет, вместо них - ID ключа и его права
+type Identity struct {
+	PublicKey string
+	SessionID string
+	TokenID   string
+	ExpiresAt time.Time // Нулевое для API-ключей: они действуют до отзыва
+	APIKeyID  string
+	Scopes    []string
+	Role      models.Role // Из токена; API-ключи всегда действуют как игрок
+}
+
+// Bot сообщает, что запрос сделан API-ключом
+func (i *Identity) Bot() bool {
+	return i.APIKeyID != ""
+}
+
+// Allows сообщает, разрешено ли действие: владельцу кошелька - всё, ключу - его права
+func (i *Identity) Allows(scope string) bool {
+	if !i.Bot() {
+		return true
+	}
+	for _, s := range i.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type Authenticator struct {
+	sessions TokenValidator
+	tokens   *token.Manager
+	apiKeys  APIKeyValidator
+	bans     BanChecker
+}
+
+func NewAuthenticator(sessions TokenValidator, tokens *token.Manager, apiKeys APIKeyValidator, bans BanChecker) *Authenticator {
+	return &Authenticator{
+		sessions: sessions,
+		tokens:   tokens,
+		apiKeys:  apiKeys,
+		bans:     bans,
+	}
+}
 
-func JWTAuth(next http.Handler) http.Handler {
+func (a *Authenticator) JWTAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cookie, err := r.Cookie("token")
+		identity, err := a.Authenticate(r)
 		if err != nil {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			WriteAuthError(w, err)
 			return
 		}
 
-		tokenStr := cookie.Value
-
-		token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			// Проверяем метод подписи
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, http.ErrAbortHandler
-			}
-			return jwtSecret, nil
-		})
+		next.ServeHTTP(w, r.WithContext(withIdentity(r.Context(), identity)))
+	})
+}
 
+// APIKeyAuth - как JWTAuth, но пропускает и ботов с API-ключом в заголовке
+// X-API-Key, если у ключа есть право scope (пустой scope - любой ключ)
+func (a *Authenticator) APIKeyAuth(scope string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, err := a.AuthenticateAPIKey(r)
+		if err == ErrNoToken {
+			identity, err = a.Authenticate(r)
+		}
 		if err != nil {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			WriteAuthError(w, err)
 			return
 		}
-
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			publicKey, ok := claims["publicKey"].(string)
-			if !ok {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
-				return
-			}
-
-			// Добавляем publicKey в контекст запроса
-			ctx := context.WithValue(r.Context(), ContextKeyPublicKey, publicKey)
-			next.ServeHTTP(w, r.WithContext(ctx))
-		} else {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		if scope != "" && !identity.Allows(scope) {
+			http.Error(w, "API key is missing the "+scope+" scope", http.StatusForbidden)
+			return
 		}
+
+		next.ServeHTTP(w, r.WithContext(withIdentity(r.Context(), identity)))
 	})
 }
+
+// WriteAuthError отвечает 403 с причиной бана или 401 для остальных ошибок
+func WriteAuthError(w http.ResponseWriter, err error) {
+	var banErr *services.BanError
+	if !errors.As(err, &banErr) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":     "banned",
+		"reason":    banErr.Ban.Reason,
+		"expiresAt": banErr.Ban.ExpiresAt,
+	})
+}
+
+// withIdentity добавляет publicKey, сессию и ключ в контекст запроса
+func withIdentity(ctx context.Context, identity *Identity) context.Context {
+	ctx = context.WithValue(ctx, ContextKeyPublicKey, identity.PublicKey)
+	ctx = context.WithValue(ctx, ContextKeySessionID, identity.SessionID)
+	ctx = context.WithValue(ctx, ContextKeyTokenID, identity.TokenID)
+	ctx = context.WithValue(ctx, ContextKeyAPIKeyID, identity.APIKeyID)
+	ctx = context.WithValue(ctx, ContextKeyRole, identity.Role)
+	return ctx
+}
+
+// AuthenticateAPIKey проверяет ключ из заголовка X-API-Key
+func (a *Authenticator) AuthenticateAPIKey(r *http.Request) (*Identity, error) {
+	raw := r.Header.Get(APIKeyHeader)
+	if raw == "" {
+		return nil, ErrNoToken
+	}
+	key, err := a.apiKeys.AuthenticateAPIKey(r.Context(), raw)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+	if err := a.checkBan(r.Context(), key.PublicKey); err != nil {
+		return nil, err
+	}
+	return &Identity{
+		PublicKey: key.PublicKey,
+		APIKeyID:  key.ID,
+		Scopes:    key.Scopes,
+		Role:      models.RolePlayer,
+	}, nil
+}
+
+// Authenticate проверяет JWT из куки "token"
+func (a *Authenticator) Authenticate(r *http.Request) (*Identity, error) {
+	cookie, err := r.Cookie("token")
+	if err != nil {
+		return nil, ErrNoToken
+	}
+	return a.parseToken(r.Context(), cookie.Value)
+}
+
+// AuthenticateWebSocket аутентифицирует запрос на апгрейд WebSocket. Браузеры
+// передают куки "token", а боты - API-ключ в X-API-Key либо токен
+// в Sec-WebSocket-Protocol или в параметре запроса "token"
+func (a *Authenticator) AuthenticateWebSocket(r *http.Request) (*Identity, error) {
+	if identity, err := a.AuthenticateAPIKey(r); err != ErrNoToken {
+		return identity, err
+	}
+	if cookie, err := r.Cookie("token"); err == nil {
+		return a.parseToken(r.Context(), cookie.Value)
+	}
+	if tokenStr := subprotocolToken(r); tokenStr != "" {
+		return a.parseToken(r.Context(), tokenStr)
+	}
+	if tokenStr := r.URL.Query().Get("token"); tokenStr != "" {
+		return a.parseToken(r.Context(), tokenStr)
+	}
+	return nil, ErrNoToken
+}
+
+// AuthenticateToken проверяет токен, присланный не в заголовках, например
+// в сообщении "auth" по уже открытому WebSocket
+func (a *Authenticator) AuthenticateToken(ctx context.Context, tokenStr string) (*Identity, error) {
+	return a.parseToken(ctx, tokenStr)
+}
+
+func subprotocolToken(r *http.Request) string {
+	var protocols []string
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(header, ",") {
+			protocols = append(protocols, strings.TrimSpace(protocol))
+		}
+	}
+	for i := 0; i+1 < len(protocols); i++ {
+		if protocols[i] == WebSocketTokenProtocol {
+			return protocols[i+1]
+		}
+	}
+	return ""
+}
+
+func (a *Authenticator) parseToken(ctx context.Context, tokenStr string) (*Identity, error) {
+	claims, err := a.tokens.Parse(tokenStr)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	// Токен валиден, пока он не отозван и жива его сессия
+	if err := a.sessions.ValidateToken(ctx, claims.Id, claims.SessionID, claims.PublicKey); err != nil {
+		return nil, ErrUnauthorized
+	}
+	if err := a.checkBan(ctx, claims.PublicKey); err != nil {
+		return nil, err
+	}
+	return &Identity{
+		PublicKey: claims.PublicKey,
+		SessionID: claims.SessionID,
+		TokenID:   claims.Id,
+		ExpiresAt: claims.Expiry(),
+		Role:      claims.Role,
+	}, nil
+}
+
+// checkBan пропускает *services.BanError как есть, чтобы показать причину бана
+func (a *Authenticator) checkBan(ctx context.Context, publicKey string) error {
+	err := a.bans.CheckAccess(ctx, publicKey)
+	if err == nil {
+		return nil
+	}
+	var banErr *services.BanError
+	if errors.As(err, &banErr) {
+		return err
+	}
+	return ErrUnauthorized
+}
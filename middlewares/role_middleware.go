@@ -0,0 +1,23 @@
+// middlewares/role_middleware.go
+
+package middlewares
+
+import (
+	"net/http"
+
+	"your_project/models"
+)
+
+// RequireRole пропускает только запросы с ролью не ниже required. Ставится
+// после JWTAuth, который кладёт роль из токена в контекст. Роль в токене
+// обновляется вместе с ним, то есть не позже чем через ACCESS_TOKEN_TTL
+func RequireRole(required models.Role, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role, _ := r.Context().Value(ContextKeyRole).(models.Role)
+		if !role.AtLeast(required) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
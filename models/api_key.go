@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Права API-ключа
+const (
+	ScopePixelsWrite = "pixels:write" // Ставить пиксели
+	ScopeTeamsWrite  = "teams:write"  // Создавать команды, вступать и выходить
+)
+
+// APIKey - ключ для ботов, выпущенный владельцем кошелька. Сам ключ
+// показывается один раз при создании, в базе хранится только его SHA-256
+type APIKey struct {
+	ID         string     `json:"id" bson:"_id"`
+	PublicKey  string     `json:"publicKey" bson:"publicKey"` // Кошелёк-владелец: ему приписываются пиксели
+	Name       string     `json:"name" bson:"name"`
+	Prefix     string     `json:"prefix" bson:"prefix"` // Начало ключа, чтобы узнать его в списке
+	Hash       string     `json:"-" bson:"hash"`
+	Scopes     []string   `json:"scopes" bson:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt" bson:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty" bson:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty" bson:"revokedAt,omitempty"`
+}
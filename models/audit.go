@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditAction - тип действия модератора или админа
+type AuditAction string
+
+const (
+	AuditRoleSet          AuditAction = "role.set"
+	AuditBanCreate        AuditAction = "ban.create"
+	AuditBanLift          AuditAction = "ban.lift"
+	AuditRollbackRegion   AuditAction = "rollback.region"
+	AuditRollbackWallet   AuditAction = "rollback.wallet"
+	AuditReportAssign     AuditAction = "report.assign"
+	AuditReportResolve    AuditAction = "report.resolve"
+	AuditZoneCreate       AuditAction = "zone.create"
+	AuditZoneUpdate       AuditAction = "zone.update"
+	AuditZoneDelete       AuditAction = "zone.delete"
+	AuditTerritoryApprove AuditAction = "territory.approve"
+	AuditTerritoryReject  AuditAction = "territory.reject"
+)
+
+// AuditTargetType - над чем совершено действие
+type AuditTargetType string
+
+const (
+	AuditTargetWallet AuditTargetType = "wallet"
+	AuditTargetTeam   AuditTargetType = "team"
+	AuditTargetRegion AuditTargetType = "region"
+	AuditTargetReport AuditTargetType = "report"
+	AuditTargetZone   AuditTargetType = "zone"
+)
+
+// Valid сообщает, известен ли тип цели
+func (t AuditTargetType) Valid() bool {
+	switch t {
+	case AuditTargetWallet, AuditTargetTeam, AuditTargetRegion, AuditTargetReport, AuditTargetZone:
+		return true
+	}
+	return false
+}
+
+// AuditTarget - цель действия: кошелёк, команда, жалоба или зона по ID, либо регион холста
+type AuditTarget struct {
+	Type   AuditTargetType `json:"type" bson:"type"`
+	ID     string          `json:"id,omitempty" bson:"id,omitempty"`
+	Region *Region         `json:"region,omitempty" bson:"region,omitempty"`
+}
+
+// AuditRequest - откуда пришёл запрос с действием
+type AuditRequest struct {
+	Method    string `json:"method" bson:"method"`
+	Path      string `json:"path" bson:"path"`
+	IPAddress string `json:"ipAddress,omitempty" bson:"ipAddress,omitempty"`
+	UserAgent string `json:"userAgent,omitempty" bson:"userAgent,omitempty"`
+}
+
+// AuditEntry - запись журнала аудита. Before и After - состояние цели до и
+// после действия (для созданий Before пуст). Записи не меняются и не удаляются
+type AuditEntry struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Actor     string             `json:"actor" bson:"actor"`
+	ActorRole Role               `json:"actorRole" bson:"actorRole"`
+	Action    AuditAction        `json:"action" bson:"action"`
+	Target    AuditTarget        `json:"target" bson:"target"`
+	Before    interface{}        `json:"before,omitempty" bson:"before,omitempty"`
+	After     interface{}        `json:"after,omitempty" bson:"after,omitempty"`
+	Request   AuditRequest       `json:"request" bson:"request"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+// AuditQuery - фильтры журнала; пустые поля не ограничивают выборку,
+// время - полуинтервал [From, To)
+type AuditQuery struct {
+	Actor      string
+	Action     AuditAction
+	TargetType AuditTargetType
+	TargetID   string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
+
+type AuditPage struct {
+	Entries []AuditEntry `json:"entries"`
+	Limit   int          `json:"limit"`
+	Offset  int          `json:"offset"`
+	HasMore bool         `json:"hasMore"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Challenge - выданное кошельку сообщение для входа (Sign-In With Solana).
+// Действует один раз и до ExpiresAt
+type Challenge struct {
+	Nonce     string    `json:"nonce" bson:"_id"`
+	PublicKey string    `json:"publicKey" bson:"publicKey"`
+	Message   string    `json:"message" bson:"message"`
+	IssuedAt  time.Time `json:"issuedAt" bson:"issuedAt"`
+	ExpiresAt time.Time `json:"expiresAt" bson:"expiresAt"`
+}
+
+// Session - вход с одного устройства. Access-токены несут ID сессии, поэтому
+// отозванная сессия перестаёт работать сразу. Сессия же - семейство
+// refresh-токенов: повторное использование любого из них отзывает её целиком
+type Session struct {
+	ID        string     `json:"id" bson:"_id"`
+	PublicKey string     `json:"publicKey" bson:"publicKey"`
+	CreatedAt time.Time  `json:"createdAt" bson:"createdAt"`
+	ExpiresAt time.Time  `json:"expiresAt" bson:"expiresAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty" bson:"revokedAt,omitempty"`
+	UserAgent string     `json:"userAgent,omitempty" bson:"userAgent,omitempty"`
+	IPAddress string     `json:"ipAddress,omitempty" bson:"ipAddress,omitempty"`
+	Current   bool       `json:"current" bson:"-"` // Сессия, из которой сделан запрос
+}
+
+// Active сообщает, можно ли ещё пользоваться сессией
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// RevokedToken - отозванный до истечения токен. Хранится, пока токен мог бы
+// ещё действовать
+type RevokedToken struct {
+	ID        string    `json:"id" bson:"_id"` // jti
+	PublicKey string    `json:"publicKey" bson:"publicKey"`
+	SessionID string    `json:"sessionId" bson:"sessionId"`
+	RevokedAt time.Time `json:"revokedAt" bson:"revokedAt"`
+	ExpiresAt time.Time `json:"expiresAt" bson:"expiresAt"`
+}
+
+// RefreshToken - одноразовый токен для получения нового access-токена.
+// Хранится только SHA-256 хэш; после использования заменяется следующим
+type RefreshToken struct {
+	Hash      string     `json:"-" bson:"_id"`
+	SessionID string     `json:"sessionId" bson:"sessionId"`
+	PublicKey string     `json:"publicKey" bson:"publicKey"`
+	CreatedAt time.Time  `json:"createdAt" bson:"createdAt"`
+	ExpiresAt time.Time  `json:"expiresAt" bson:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt,omitempty" bson:"usedAt,omitempty"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BanType - что запрещено кошельку
+type BanType string
+
+const (
+	BanPlacement BanType = "placement" // Нельзя ставить пиксели и вступать в команды
+	BanFull      BanType = "full"      // Нельзя ничего, что требует входа
+)
+
+// Ban - бан кошелька. Записи не удаляются: снятый или истёкший бан остаётся
+// в истории вместе с тем, кто его выдал и снял
+type Ban struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	PublicKey  string             `json:"publicKey" bson:"publicKey"`
+	Type       BanType            `json:"type" bson:"type"`
+	Reason     string             `json:"reason" bson:"reason"`
+	IssuedBy   string             `json:"issuedBy" bson:"issuedBy"`
+	IssuedAt   time.Time          `json:"issuedAt" bson:"issuedAt"`
+	ExpiresAt  *time.Time         `json:"expiresAt,omitempty" bson:"expiresAt,omitempty"` // nil - бессрочный
+	LiftedBy   string             `json:"liftedBy,omitempty" bson:"liftedBy,omitempty"`
+	LiftedAt   *time.Time         `json:"liftedAt,omitempty" bson:"liftedAt,omitempty"`
+	LiftReason string             `json:"liftReason,omitempty" bson:"liftReason,omitempty"`
+}
+
+// Active сообщает, действует ли бан
+func (b *Ban) Active(now time.Time) bool {
+	return b.LiftedAt == nil && (b.ExpiresAt == nil || now.Before(*b.ExpiresAt))
+}
+
+// BanQuery - выборка банов для модераторов
+type BanQuery struct {
+	PublicKey  string
+	ActiveOnly bool
+	Limit      int
+	Offset     int
+}
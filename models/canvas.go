@@ -0,0 +1,27 @@
+package models
+
+// Canvas - метаданные холста, которые получают клиенты
+type Canvas struct {
+	Width       int                 `json:"width"`
+	Height      int                 `json:"height"`
+	Territories []TerritoryBoundary `json:"territories"` // Действующие территории команд
+}
+
+// Contains сообщает, лежит ли клетка (x, y) внутри холста
+func (c Canvas) Contains(x, y int) bool {
+	return x >= 0 && y >= 0 && x < c.Width && y < c.Height
+}
+
+// Region - прямоугольник холста
+type Region struct {
+	X      int `json:"x" bson:"x"`
+	Y      int `json:"y" bson:"y"`
+	Width  int `json:"width" bson:"width"`
+	Height int `json:"height" bson:"height"`
+}
+
+// Overlaps сообщает, пересекаются ли прямоугольники
+func (r Region) Overlaps(other Region) bool {
+	return r.X < other.X+other.Width && other.X < r.X+r.Width &&
+		r.Y < other.Y+other.Height && other.Y < r.Y+r.Height
+}
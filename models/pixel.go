@@ -1,7 +1,15 @@
 package models
 
+import "time"
+
 type Pixel struct {
 	X     int    `json:"x" bson:"x"`
 	Y     int    `json:"y" bson:"y"`
 	Color string `json:"color" bson:"color"`
+	// ColorIndex - индекс цвета в палитре; клиент может прислать его вместо Color
+	ColorIndex *int      `json:"colorIndex,omitempty" bson:"-"`
+	PublicKey  string    `json:"publicKey,omitempty" bson:"publicKey"`
+	TeamID     string    `json:"teamId,omitempty" bson:"teamId"`
+	Bot        bool      `json:"bot,omitempty" bson:"bot"` // Поставлен через API-ключ
+	PlacedAt   time.Time `json:"placedAt" bson:"placedAt"`
 }
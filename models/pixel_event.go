@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PixelEvent - запись в неизменяемой истории установок пикселей. Откат тоже
+// пишется событием: RollbackBy - кто откатил, пустой Color - клетка очищена
+type PixelEvent struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	X         int                `json:"x" bson:"x"`
+	Y         int                `json:"y" bson:"y"`
+	Color     string             `json:"color" bson:"color"`
+	PublicKey string             `json:"publicKey,omitempty" bson:"publicKey"`
+	TeamID    string             `json:"teamId,omitempty" bson:"teamId"`
+	Bot       bool               `json:"bot,omitempty" bson:"bot,omitempty"`
+	// RollbackBy - кошелёк админа, если событие записано откатом
+	RollbackBy string    `json:"rollbackBy,omitempty" bson:"rollbackBy,omitempty"`
+	PlacedAt   time.Time `json:"placedAt" bson:"placedAt"`
+}
+
+// PixelHistoryQuery описывает выборку из истории: прямоугольник (для одной
+// клетки Width = Height = 1) и полуинтервал времени [From, To)
+type PixelHistoryQuery struct {
+	X      int
+	Y      int
+	Width  int
+	Height int
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Offset int
+}
+
+type PixelHistoryPage struct {
+	Events  []PixelEvent `json:"events"`
+	Limit   int          `json:"limit"`
+	Offset  int          `json:"offset"`
+	HasMore bool         `json:"hasMore"`
+}
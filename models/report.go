@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReportType - на что жалуется игрок
+type ReportType string
+
+const (
+	ReportRegion ReportType = "region" // Оскорбительный рисунок в области холста
+	ReportWallet ReportType = "wallet" // Кошелёк, который портит холст
+)
+
+// ReportStatus - состояние жалобы в очереди модерации
+type ReportStatus string
+
+const (
+	ReportOpen      ReportStatus = "open"
+	ReportActioned  ReportStatus = "actioned"  // Модератор принял меры
+	ReportDismissed ReportStatus = "dismissed" // Жалоба отклонена
+)
+
+// Report - жалоба игрока. Для области запоминаются миниатюра и установки,
+// видимые в ней на момент жалобы, чтобы модератор видел то же, что игрок
+type Report struct {
+	ID            primitive.ObjectID   `json:"id" bson:"_id,omitempty"`
+	Reporter      string               `json:"reporter" bson:"reporter"`
+	Type          ReportType           `json:"type" bson:"type"`
+	PublicKey     string               `json:"publicKey,omitempty" bson:"publicKey,omitempty"` // Кошелёк, на который жалуются
+	Region        *Region              `json:"region,omitempty" bson:"region,omitempty"`
+	Reason        string               `json:"reason" bson:"reason"`
+	Thumbnail     []byte               `json:"-" bson:"thumbnail,omitempty"` // PNG области
+	CanvasVersion uint64               `json:"canvasVersion,omitempty" bson:"canvasVersion,omitempty"`
+	EventIDs      []primitive.ObjectID `json:"eventIds,omitempty" bson:"eventIds,omitempty"`
+	Status        ReportStatus         `json:"status" bson:"status"`
+	AssignedTo    string               `json:"assignedTo,omitempty" bson:"assignedTo,omitempty"`
+	AssignedAt    *time.Time           `json:"assignedAt,omitempty" bson:"assignedAt,omitempty"`
+	ResolvedBy    string               `json:"resolvedBy,omitempty" bson:"resolvedBy,omitempty"`
+	ResolvedAt    *time.Time           `json:"resolvedAt,omitempty" bson:"resolvedAt,omitempty"`
+	Resolution    string               `json:"resolution,omitempty" bson:"resolution,omitempty"` // Комментарий модератора
+	CreatedAt     time.Time            `json:"createdAt" bson:"createdAt"`
+}
+
+// ReportQuery - выборка из очереди модерации
+type ReportQuery struct {
+	Status     ReportStatus
+	AssignedTo string
+	Limit      int
+	Offset     int
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Role - уровень прав кошелька. Каждая следующая роль включает права предыдущей
+type Role string
+
+const (
+	RolePlayer    Role = "player"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+var roleRank = map[Role]int{
+	RolePlayer:    1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// Valid сообщает, известна ли роль
+func (r Role) Valid() bool {
+	_, ok := roleRank[r]
+	return ok
+}
+
+// AtLeast сообщает, не ниже ли роль, чем required
+func (r Role) AtLeast(required Role) bool {
+	return roleRank[r] >= roleRank[required]
+}
+
+// UserRole - роль кошелька. Кошельки без записи - игроки
+type UserRole struct {
+	PublicKey string    `json:"publicKey" bson:"_id"`
+	Role      Role      `json:"role" bson:"role"`
+	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+	UpdatedBy string    `json:"updatedBy,omitempty" bson:"updatedBy,omitempty"` // Пусто - назначена из конфигурации
+}
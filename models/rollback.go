@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Cell - координаты клетки холста
+type Cell struct {
+	X int `json:"x" bson:"x"`
+	Y int `json:"y" bson:"y"`
+}
+
+// CellStateQuery - выборка последнего события каждой клетки прямоугольника.
+// Before ограничивает события моментом (включительно), а Exclude* выкидывают
+// установки одного кошелька за полуинтервал [ExcludeFrom, ExcludeTo)
+// (события откатов с его авторством остаются)
+type CellStateQuery struct {
+	X                int
+	Y                int
+	Width            int
+	Height           int
+	Before           time.Time
+	ExcludePublicKey string
+	ExcludeFrom      time.Time
+	ExcludeTo        time.Time
+}
+
+// RollbackResult - итог отката. Changed - клетки, которые изменились
+// (очищенные - с пустым Color и без ColorIndex), Replaced - их состояние
+// до отката в том же порядке (пустая клетка - с пустым Color)
+type RollbackResult struct {
+	Changed  []Pixel `json:"-"`
+	Replaced []Pixel `json:"-"`
+	Count    int     `json:"changed"`
+	DryRun   bool    `json:"dryRun"`
+}
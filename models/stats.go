@@ -0,0 +1,58 @@
+// models/stats.go
+package models
+
+import "time"
+
+// StatsScope - чьи это счётчики: кошелька или команды
+type StatsScope string
+
+const (
+	StatsWallet StatsScope = "wallet"
+	StatsTeam   StatsScope = "team"
+)
+
+// LeaderboardSort - по какому счётчику строится таблица лидеров
+type LeaderboardSort string
+
+const (
+	SortByTotal    LeaderboardSort = "total"
+	SortByOwned    LeaderboardSort = "owned"
+	SortByLastHour LeaderboardSort = "hour"
+	SortByLastDay  LeaderboardSort = "day"
+)
+
+// PlacementStats - счётчики кошелька или команды, обновляемые на каждую установку.
+// LastHour и LastDay считаются по корзинам установок и в документе не хранятся
+type PlacementStats struct {
+	Scope        StatsScope `json:"-" bson:"scope"`
+	Key          string     `json:"-" bson:"key"`                   // publicKey или ID команды
+	TeamID       string     `json:"teamId,omitempty" bson:"teamId"` // Для кошелька - команда последней установки
+	Total        int64      `json:"total" bson:"total"`             // Всего установок
+	Owned        int64      `json:"owned" bson:"owned"`             // Клеток на холсте сейчас
+	LastHour     int64      `json:"lastHour" bson:"-"`              // Установок за последний час
+	LastDay      int64      `json:"lastDay" bson:"-"`               // Установок за последние сутки
+	LastPlacedAt time.Time  `json:"lastPlacedAt" bson:"lastPlacedAt"`
+}
+
+// WindowCount - число установок ключа за окно времени
+type WindowCount struct {
+	Key   string `bson:"_id"`
+	Count int64  `bson:"count"`
+}
+
+type PlayerStats struct {
+	Rank      int    `json:"rank"`
+	PublicKey string `json:"publicKey"`
+	PlacementStats
+}
+
+type TeamStats struct {
+	Rank     int    `json:"rank"`
+	TeamName string `json:"teamName"`
+	PlacementStats
+}
+
+type LeaderboardQuery struct {
+	Sort  LeaderboardSort
+	Limit int
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TerritoryStatus - состояние заявки команды на территорию
+type TerritoryStatus string
+
+const (
+	TerritoryPending  TerritoryStatus = "pending"  // Ждёт решения админа
+	TerritoryApproved TerritoryStatus = "approved" // Действует
+	TerritoryRejected TerritoryStatus = "rejected" // Отклонена или отозвана админом
+	TerritoryReleased TerritoryStatus = "released" // Команда отказалась сама
+)
+
+// TerritoryPolicy - что происходит с чужими установками на территории
+type TerritoryPolicy string
+
+const (
+	TerritoryRestricted TerritoryPolicy = "restricted" // Ставить могут только участники команды
+	TerritoryPenalty    TerritoryPolicy = "penalty"    // Чужим - удлинённая пауза
+)
+
+// Territory - прямоугольник холста, закреплённый за командой
+type Territory struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	TeamID      string             `json:"teamId" bson:"teamId"`
+	TeamName    string             `json:"teamName" bson:"teamName"`
+	Region      Region             `json:"region" bson:"region"`
+	Policy      TerritoryPolicy    `json:"policy" bson:"policy"`
+	Status      TerritoryStatus    `json:"status" bson:"status"`
+	RequestedBy string             `json:"requestedBy" bson:"requestedBy"`
+	RequestedAt time.Time          `json:"requestedAt" bson:"requestedAt"`
+	ReviewedBy  string             `json:"reviewedBy,omitempty" bson:"reviewedBy,omitempty"` // Админ или участник, снявший заявку
+	ReviewedAt  *time.Time         `json:"reviewedAt,omitempty" bson:"reviewedAt,omitempty"`
+	ReviewNote  string             `json:"reviewNote,omitempty" bson:"reviewNote,omitempty"`
+}
+
+// Contains сообщает, лежит ли клетка (x, y) на территории
+func (t *Territory) Contains(x, y int) bool {
+	r := t.Region
+	return x >= r.X && y >= r.Y && x < r.X+r.Width && y < r.Y+r.Height
+}
+
+// Boundary - граница территории для метаданных холста
+func (t *Territory) Boundary() TerritoryBoundary {
+	return TerritoryBoundary{
+		ID:       t.ID.Hex(),
+		TeamID:   t.TeamID,
+		TeamName: t.TeamName,
+		Region:   t.Region,
+		Policy:   t.Policy,
+	}
+}
+
+// TerritoryBoundary - действующая территория в том виде, в каком её видят клиенты
+type TerritoryBoundary struct {
+	ID       string          `json:"id"`
+	TeamID   string          `json:"teamId"`
+	TeamName string          `json:"teamName"`
+	Region   Region          `json:"region"`
+	Policy   TerritoryPolicy `json:"policy"`
+}
+
+// TerritoryQuery - выборка заявок; пустые поля не ограничивают
+type TerritoryQuery struct {
+	TeamID string
+	Status TerritoryStatus
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ZoneShape - форма защищённой зоны
+type ZoneShape string
+
+const (
+	ZoneRect    ZoneShape = "rect"
+	ZonePolygon ZoneShape = "polygon"
+)
+
+// Zone - защищённая область холста (спонсорский или ивентовый рисунок).
+// Пока зона действует, ставить в неё могут только команды из AllowedTeams.
+// Вершины Polygon - углы клеток, клетка внутри, если внутри её центр
+type Zone struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name         string             `json:"name" bson:"name"`
+	Shape        ZoneShape          `json:"shape" bson:"shape"`
+	Region       *Region            `json:"region,omitempty" bson:"region,omitempty"`
+	Polygon      []Cell             `json:"polygon,omitempty" bson:"polygon,omitempty"`
+	StartsAt     *time.Time         `json:"startsAt,omitempty" bson:"startsAt,omitempty"` // Пусто - сразу
+	EndsAt       *time.Time         `json:"endsAt,omitempty" bson:"endsAt,omitempty"`     // Пусто - бессрочно
+	AllowedTeams []string           `json:"allowedTeams" bson:"allowedTeams"`
+	CreatedBy    string             `json:"createdBy" bson:"createdBy"`
+	CreatedAt    time.Time          `json:"createdAt" bson:"createdAt"`
+	UpdatedAt    time.Time          `json:"updatedAt" bson:"updatedAt"`
+}
+
+// ActiveAt сообщает, действует ли зона в момент now
+func (z *Zone) ActiveAt(now time.Time) bool {
+	if z.StartsAt != nil && now.Before(*z.StartsAt) {
+		return false
+	}
+	return z.EndsAt == nil || now.Before(*z.EndsAt)
+}
+
+// Allows сообщает, может ли команда ставить пиксели в зону
+func (z *Zone) Allows(teamID string) bool {
+	if teamID == "" {
+		return false
+	}
+	for _, allowed := range z.AllowedTeams {
+		if allowed == teamID {
+			return true
+		}
+	}
+	return false
+}
+
+// Bounds возвращает охватывающий прямоугольник зоны
+func (z *Zone) Bounds() Region {
+	if z.Shape == ZoneRect && z.Region != nil {
+		return *z.Region
+	}
+	if len(z.Polygon) == 0 {
+		return Region{}
+	}
+	minX, minY, maxX, maxY := z.Polygon[0].X, z.Polygon[0].Y, z.Polygon[0].X, z.Polygon[0].Y
+	for _, p := range z.Polygon[1:] {
+		minX, minY = min(minX, p.X), min(minY, p.Y)
+		maxX, maxY = max(maxX, p.X), max(maxY, p.Y)
+	}
+	return Region{X: minX, Y: minY, Width: maxX - minX, Height: maxY - minY}
+}
+
+// Contains сообщает, лежит ли клетка (x, y) в зоне
+func (z *Zone) Contains(x, y int) bool {
+	if z.Shape == ZoneRect {
+		r := z.Region
+		return r != nil && x >= r.X && y >= r.Y && x < r.X+r.Width && y < r.Y+r.Height
+	}
+
+	// Луч из центра клетки вправо: внутри, если он пересекает нечётное число рёбер.
+	// Координаты удвоены, чтобы центр (x+0.5, y+0.5) остался целым
+	px, py := 2*x+1, 2*y+1
+	inside := false
+	for i, j := 0, len(z.Polygon)-1; i < len(z.Polygon); j, i = i, i+1 {
+		ax, ay := 2*z.Polygon[i].X, 2*z.Polygon[i].Y
+		bx, by := 2*z.Polygon[j].X, 2*z.Polygon[j].Y
+		if (ay > py) == (by > py) {
+			continue
+		}
+		// Пересечение левее или правее центра: сравниваем без деления
+		cross := (bx-ax)*(py-ay) - (px-ax)*(by-ay)
+		if cross == 0 {
+			return true // Центр на ребре - клетку защищаем
+		}
+		if (by > ay) == (cross > 0) {
+			inside = !inside
+		}
+	}
+	return inside
+}
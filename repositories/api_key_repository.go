@@ -0,0 +1,102 @@
+// repositories/api_key_repository.go
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"your_project/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type APIKeyRepository interface {
+	CreateKey(ctx context.Context, key *models.APIKey) error
+	GetKeyByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	// GetKeys возвращает неотозванные ключи кошелька
+	GetKeys(ctx context.Context, publicKey string) ([]models.APIKey, error)
+	CountKeys(ctx context.Context, publicKey string) (int64, error)
+	RevokeKey(ctx context.Context, publicKey, id string) error
+	TouchKey(ctx context.Context, id string, usedAt time.Time) error
+	EnsureIndexes(ctx context.Context) error
+}
+
+type apiKeyRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAPIKeyRepository(db *mongo.Database) APIKeyRepository {
+	return &apiKeyRepository{
+		collection: db.Collection("api_keys"),
+	}
+}
+
+func (kr *apiKeyRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := kr.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "publicKey", Value: 1}}},
+	})
+	return err
+}
+
+func (kr *apiKeyRepository) CreateKey(ctx context.Context, key *models.APIKey) error {
+	_, err := kr.collection.InsertOne(ctx, key)
+	return err
+}
+
+func (kr *apiKeyRepository) GetKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := kr.collection.FindOne(ctx, bson.M{"hash": hash}).Decode(&key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (kr *apiKeyRepository) GetKeys(ctx context.Context, publicKey string) ([]models.APIKey, error) {
+	filter := bson.M{
+		"publicKey": publicKey,
+		"revokedAt": bson.M{"$exists": false},
+	}
+	cursor, err := kr.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var keys []models.APIKey
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (kr *apiKeyRepository) CountKeys(ctx context.Context, publicKey string) (int64, error) {
+	return kr.collection.CountDocuments(ctx, bson.M{
+		"publicKey": publicKey,
+		"revokedAt": bson.M{"$exists": false},
+	})
+}
+
+func (kr *apiKeyRepository) RevokeKey(ctx context.Context, publicKey, id string) error {
+	filter := bson.M{
+		"_id":       id,
+		"publicKey": publicKey,
+		"revokedAt": bson.M{"$exists": false},
+	}
+	update := bson.M{"$set": bson.M{"revokedAt": time.Now()}}
+	result, err := kr.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (kr *apiKeyRepository) TouchKey(ctx context.Context, id string, usedAt time.Time) error {
+	_, err := kr.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"lastUsedAt": usedAt}})
+	return err
+}
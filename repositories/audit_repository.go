@@ -0,0 +1,120 @@
+// repositories/audit_repository.go
+package repositories
+
+import (
+	"context"
+	"reflect"
+
+	"your_project/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AuditRepository interface {
+	InsertEntry(ctx context.Context, entry *models.AuditEntry) error
+	// GetEntries возвращает страницу журнала, новые записи первыми
+	GetEntries(ctx context.Context, query models.AuditQuery) ([]models.AuditEntry, error)
+	// EachEntry обходит все подходящие записи от старых к новым, не загружая их в память
+	EachEntry(ctx context.Context, query models.AuditQuery, fn func(*models.AuditEntry) error) error
+	EnsureIndexes(ctx context.Context) error
+}
+
+type auditRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAuditRepository(db *mongo.Database) AuditRepository {
+	// Before/After хранятся документами произвольной формы; читаем их как
+	// bson.M, чтобы в JSON они выглядели объектами, а не списками пар
+	registry := bson.NewRegistry()
+	registry.RegisterTypeMapEntry(bson.TypeEmbeddedDocument, reflect.TypeOf(bson.M{}))
+
+	return &auditRepository{
+		collection: db.Collection("audit_log", options.Collection().SetRegistry(registry)),
+	}
+}
+
+func (ar *auditRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := ar.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "actor", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "target.id", Value: 1}, {Key: "createdAt", Value: -1}}},
+	})
+	return err
+}
+
+func (ar *auditRepository) InsertEntry(ctx context.Context, entry *models.AuditEntry) error {
+	_, err := ar.collection.InsertOne(ctx, entry)
+	return err
+}
+
+func (ar *auditRepository) GetEntries(ctx context.Context, query models.AuditQuery) ([]models.AuditEntry, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64(query.Offset))
+	if query.Limit > 0 {
+		opts.SetLimit(int64(query.Limit))
+	}
+
+	cursor, err := ar.collection.Find(ctx, auditFilter(query), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.AuditEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (ar *auditRepository) EachEntry(ctx context.Context, query models.AuditQuery, fn func(*models.AuditEntry) error) error {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := ar.collection.Find(ctx, auditFilter(query), opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var entry models.AuditEntry
+		if err := cursor.Decode(&entry); err != nil {
+			return err
+		}
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+func auditFilter(query models.AuditQuery) bson.M {
+	filter := bson.M{}
+	if query.Actor != "" {
+		filter["actor"] = query.Actor
+	}
+	if query.Action != "" {
+		filter["action"] = query.Action
+	}
+	if query.TargetType != "" {
+		filter["target.type"] = query.TargetType
+	}
+	if query.TargetID != "" {
+		filter["target.id"] = query.TargetID
+	}
+
+	createdAt := bson.M{}
+	if !query.From.IsZero() {
+		createdAt["$gte"] = query.From
+	}
+	if !query.To.IsZero() {
+		createdAt["$lt"] = query.To
+	}
+	if len(createdAt) > 0 {
+		filter["createdAt"] = createdAt
+	}
+	return filter
+}
@@ -0,0 +1,127 @@
+// repositories/ban_repository.go
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"your_project/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type BanRepository interface {
+	CreateBan(ctx context.Context, ban *models.Ban) error
+	// GetActiveBans возвращает действующие на момент now баны кошелька
+	GetActiveBans(ctx context.Context, publicKey string, now time.Time) ([]models.Ban, error)
+	GetBans(ctx context.Context, query models.BanQuery, now time.Time) ([]models.Ban, error)
+	GetBan(ctx context.Context, id primitive.ObjectID) (*models.Ban, error)
+	// LiftBan снимает бан и возвращает его; снятый ранее даст mongo.ErrNoDocuments
+	LiftBan(ctx context.Context, id primitive.ObjectID, liftedBy, reason string, at time.Time) (*models.Ban, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+type banRepository struct {
+	collection *mongo.Collection
+}
+
+func NewBanRepository(db *mongo.Database) BanRepository {
+	return &banRepository{
+		collection: db.Collection("bans"),
+	}
+}
+
+func (br *banRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := br.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "publicKey", Value: 1}, {Key: "issuedAt", Value: -1}},
+	})
+	return err
+}
+
+func (br *banRepository) CreateBan(ctx context.Context, ban *models.Ban) error {
+	result, err := br.collection.InsertOne(ctx, ban)
+	if err != nil {
+		return err
+	}
+	ban.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// activeFilter - не снят и не истёк
+func activeFilter(now time.Time) bson.M {
+	return bson.M{
+		"liftedAt": bson.M{"$exists": false},
+		"$or": bson.A{
+			bson.M{"expiresAt": bson.M{"$exists": false}},
+			bson.M{"expiresAt": bson.M{"$gt": now}},
+		},
+	}
+}
+
+func (br *banRepository) GetActiveBans(ctx context.Context, publicKey string, now time.Time) ([]models.Ban, error) {
+	filter := activeFilter(now)
+	filter["publicKey"] = publicKey
+	return br.find(ctx, filter, options.Find())
+}
+
+func (br *banRepository) GetBans(ctx context.Context, query models.BanQuery, now time.Time) ([]models.Ban, error) {
+	filter := bson.M{}
+	if query.ActiveOnly {
+		filter = activeFilter(now)
+	}
+	if query.PublicKey != "" {
+		filter["publicKey"] = query.PublicKey
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "issuedAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64(query.Offset)).
+		SetLimit(int64(query.Limit))
+	return br.find(ctx, filter, opts)
+}
+
+func (br *banRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.Ban, error) {
+	cursor, err := br.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var bans []models.Ban
+	if err := cursor.All(ctx, &bans); err != nil {
+		return nil, err
+	}
+	return bans, nil
+}
+
+func (br *banRepository) GetBan(ctx context.Context, id primitive.ObjectID) (*models.Ban, error) {
+	var ban models.Ban
+	if err := br.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&ban); err != nil {
+		return nil, err
+	}
+	return &ban, nil
+}
+
+func (br *banRepository) LiftBan(ctx context.Context, id primitive.ObjectID, liftedBy, reason string, at time.Time) (*models.Ban, error) {
+	filter := bson.M{
+		"_id":      id,
+		"liftedAt": bson.M{"$exists": false},
+	}
+	update := bson.M{"$set": bson.M{
+		"liftedBy":   liftedBy,
+		"liftedAt":   at,
+		"liftReason": reason,
+	}}
+
+	var ban models.Ban
+	err := br.collection.FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&ban)
+	if err != nil {
+		return nil, err
+	}
+	return &ban, nil
+}
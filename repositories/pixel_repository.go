@@ -2,29 +2,134 @@ package repositories
 
 import (
 	"context"
+	"time"
 
 	"your_project/models"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type PixelRepository interface {
 	GetAllPixels(ctx context.Context) ([]models.Pixel, error)
-	UpsertPixel(ctx context.Context, pixel models.Pixel) error
+	GetPixel(ctx context.Context, x, y int) (*models.Pixel, error)
+	// UpsertPixel записывает пиксель и возвращает прежний (nil, если клетка была пуста)
+	UpsertPixel(ctx context.Context, pixel models.Pixel) (*models.Pixel, error)
+	InsertPixelEvent(ctx context.Context, event *models.PixelEvent) error
+	GetPixelHistory(ctx context.Context, query models.PixelHistoryQuery) ([]models.PixelEvent, error)
+	GetEventsByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.PixelEvent, error)
+	DistinctColors(ctx context.Context) ([]string, error)
+	ReplaceColor(ctx context.Context, from, to string) (pixels int64, events int64, err error)
+	GetPixelsInRegion(ctx context.Context, x, y, width, height int) ([]models.Pixel, error)
+	// GetCellStates возвращает последнее подходящее событие каждой клетки
+	GetCellStates(ctx context.Context, query models.CellStateQuery) ([]models.PixelEvent, error)
+	// GetWalletCells возвращает клетки, которые кошелёк ставил в [from, to).
+	// События откатов его установками не считаются
+	GetWalletCells(ctx context.Context, publicKey string, from, to time.Time) ([]models.Cell, error)
+	// RestorePixels записывает пиксели пачкой; пиксель с пустым Color удаляется
+	RestorePixels(ctx context.Context, pixels []models.Pixel) error
+	InsertPixelEvents(ctx context.Context, events []models.PixelEvent) error
+	EnsureIndexes(ctx context.Context) error
 }
 
 type pixelRepository struct {
-	collection *mongo.Collection
+	collection       *mongo.Collection
+	eventsCollection *mongo.Collection
 }
 
 func NewPixelRepository(db *mongo.Database) PixelRepository {
 	return &pixelRepository{
-		collection: db.Collection("pixels"),
+		collection:       db.Collection("pixels"),
+		eventsCollection: db.Collection("pixel_events"),
 	}
 }
 
+// cellIndex - уникальный индекс клетки. Без уникальности параллельные upsert
+// в пустую клетку вставляли по документу каждый
+const cellIndex = "x_1_y_1"
+
+func (pr *pixelRepository) EnsureIndexes(ctx context.Context) error {
+	if err := pr.ensureCellIndex(ctx); err != nil {
+		return err
+	}
+
+	_, err := pr.eventsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "x", Value: 1}, {Key: "y", Value: 1}, {Key: "placedAt", Value: -1}}},
+		{Keys: bson.D{{Key: "placedAt", Value: -1}}},
+	})
+	return err
+}
+
+// ensureCellIndex заменяет прежний неуникальный индекс клетки уникальным:
+// MongoDB не даст создать его поверх старого с теми же ключами, а дубликаты,
+// оставшиеся от гонки upsert, сначала нужно удалить
+func (pr *pixelRepository) ensureCellIndex(ctx context.Context) error {
+	specs, err := pr.collection.Indexes().ListSpecifications(ctx)
+	if err != nil {
+		return err
+	}
+	for _, spec := range specs {
+		if spec.Name != cellIndex {
+			continue
+		}
+		if spec.Unique != nil && *spec.Unique {
+			return nil
+		}
+		if _, err := pr.collection.Indexes().DropOne(ctx, cellIndex); err != nil {
+			return err
+		}
+	}
+
+	if err := pr.removeDuplicateCells(ctx); err != nil {
+		return err
+	}
+	_, err = pr.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "x", Value: 1}, {Key: "y", Value: 1}},
+		Options: options.Index().SetName(cellIndex).SetUnique(true),
+	})
+	return err
+}
+
+// removeDuplicateCells оставляет в каждой клетке самый новый документ
+// (по placedAt, затем по _id) и удаляет остальные
+func (pr *pixelRepository) removeDuplicateCells(ctx context.Context) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$sort", Value: bson.D{{Key: "placedAt", Value: -1}, {Key: "_id", Value: -1}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "x", Value: "$x"}, {Key: "y", Value: "$y"}}},
+			{Key: "ids", Value: bson.D{{Key: "$push", Value: "$_id"}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+	}
+	cursor, err := pr.collection.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var stale []interface{}
+	for cursor.Next(ctx) {
+		var cell struct {
+			IDs []interface{} `bson:"ids"`
+		}
+		if err := cursor.Decode(&cell); err != nil {
+			return err
+		}
+		stale = append(stale, cell.IDs[1:]...)
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	_, err = pr.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": stale}})
+	return err
+}
+
 func (pr *pixelRepository) GetAllPixels(ctx context.Context) ([]models.Pixel, error) {
 	cursor, err := pr.collection.Find(ctx, bson.D{})
 	if err != nil {
@@ -39,10 +144,247 @@ func (pr *pixelRepository) GetAllPixels(ctx context.Context) ([]models.Pixel, er
 	return pixels, nil
 }
 
-func (pr *pixelRepository) UpsertPixel(ctx context.Context, pixel models.Pixel) error {
+func (pr *pixelRepository) GetPixel(ctx context.Context, x, y int) (*models.Pixel, error) {
+	var pixel models.Pixel
+	if err := pr.collection.FindOne(ctx, bson.M{"x": x, "y": y}).Decode(&pixel); err != nil {
+		return nil, err
+	}
+	return &pixel, nil
+}
+
+func (pr *pixelRepository) UpsertPixel(ctx context.Context, pixel models.Pixel) (*models.Pixel, error) {
 	filter := bson.M{"x": pixel.X, "y": pixel.Y}
 	update := bson.M{"$set": pixel}
-	options := options.Update().SetUpsert(true)
-	_, err := pr.collection.UpdateOne(ctx, filter, update, options)
+	options := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before)
+
+	var previous models.Pixel
+	if err := pr.collection.FindOneAndUpdate(ctx, filter, update, options).Decode(&previous); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &previous, nil
+}
+
+func (pr *pixelRepository) InsertPixelEvent(ctx context.Context, event *models.PixelEvent) error {
+	result, err := pr.eventsCollection.InsertOne(ctx, event)
+	if err != nil {
+		return err
+	}
+	event.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (pr *pixelRepository) GetPixelHistory(ctx context.Context, query models.PixelHistoryQuery) ([]models.PixelEvent, error) {
+	filter := bson.M{
+		"x": bson.M{"$gte": query.X, "$lt": query.X + query.Width},
+		"y": bson.M{"$gte": query.Y, "$lt": query.Y + query.Height},
+	}
+
+	placedAt := bson.M{}
+	if !query.From.IsZero() {
+		placedAt["$gte"] = query.From
+	}
+	if !query.To.IsZero() {
+		placedAt["$lt"] = query.To
+	}
+	if len(placedAt) > 0 {
+		filter["placedAt"] = placedAt
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "placedAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64(query.Offset))
+	if query.Limit > 0 {
+		findOptions.SetLimit(int64(query.Limit))
+	}
+
+	cursor, err := pr.eventsCollection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var events []models.PixelEvent
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (pr *pixelRepository) GetEventsByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.PixelEvent, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	filter := bson.M{"_id": bson.M{"$in": ids}}
+	opts := options.Find().SetSort(bson.D{{Key: "y", Value: 1}, {Key: "x", Value: 1}})
+	cursor, err := pr.eventsCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var events []models.PixelEvent
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// DistinctColors возвращает все цвета, встречающиеся в текущем состоянии и в истории
+func (pr *pixelRepository) DistinctColors(ctx context.Context) ([]string, error) {
+	seen := make(map[string]bool)
+	var colors []string
+	for _, collection := range []*mongo.Collection{pr.collection, pr.eventsCollection} {
+		values, err := collection.Distinct(ctx, "color", bson.D{})
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range values {
+			if color, ok := v.(string); ok && !seen[color] {
+				seen[color] = true
+				colors = append(colors, color)
+			}
+		}
+	}
+	return colors, nil
+}
+
+// ReplaceColor заменяет цвет во всех пикселях и событиях истории
+func (pr *pixelRepository) ReplaceColor(ctx context.Context, from, to string) (int64, int64, error) {
+	filter := bson.M{"color": from}
+	update := bson.M{"$set": bson.M{"color": to}}
+
+	pixelsResult, err := pr.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, 0, err
+	}
+	eventsResult, err := pr.eventsCollection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return pixelsResult.ModifiedCount, 0, err
+	}
+	return pixelsResult.ModifiedCount, eventsResult.ModifiedCount, nil
+}
+
+func (pr *pixelRepository) GetPixelsInRegion(ctx context.Context, x, y, width, height int) ([]models.Pixel, error) {
+	filter := bson.M{
+		"x": bson.M{"$gte": x, "$lt": x + width},
+		"y": bson.M{"$gte": y, "$lt": y + height},
+	}
+	cursor, err := pr.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var pixels []models.Pixel
+	if err := cursor.All(ctx, &pixels); err != nil {
+		return nil, err
+	}
+	return pixels, nil
+}
+
+func (pr *pixelRepository) GetCellStates(ctx context.Context, query models.CellStateQuery) ([]models.PixelEvent, error) {
+	match := bson.M{
+		"x": bson.M{"$gte": query.X, "$lt": query.X + query.Width},
+		"y": bson.M{"$gte": query.Y, "$lt": query.Y + query.Height},
+	}
+	if !query.Before.IsZero() {
+		match["placedAt"] = bson.M{"$lte": query.Before}
+	}
+	if query.ExcludePublicKey != "" {
+		// События откатов несут автора восстановленного пикселя, но это не его установки
+		match["$nor"] = bson.A{bson.M{
+			"publicKey":  query.ExcludePublicKey,
+			"placedAt":   bson.M{"$gte": query.ExcludeFrom, "$lt": query.ExcludeTo},
+			"rollbackBy": bson.M{"$exists": false},
+		}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{
+			{Key: "x", Value: 1}, {Key: "y", Value: 1}, {Key: "placedAt", Value: -1}, {Key: "_id", Value: -1},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "x", Value: "$x"}, {Key: "y", Value: "$y"}}},
+			{Key: "event", Value: bson.D{{Key: "$first", Value: "$$ROOT"}}},
+		}}},
+		{{Key: "$replaceRoot", Value: bson.D{{Key: "newRoot", Value: "$event"}}}},
+	}
+	cursor, err := pr.eventsCollection.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var events []models.PixelEvent
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (pr *pixelRepository) GetWalletCells(ctx context.Context, publicKey string, from, to time.Time) ([]models.Cell, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"publicKey":  publicKey,
+			"placedAt":   bson.M{"$gte": from, "$lt": to},
+			"rollbackBy": bson.M{"$exists": false},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "x", Value: "$x"}, {Key: "y", Value: "$y"}}},
+		}}},
+	}
+	cursor, err := pr.eventsCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		Cell models.Cell `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+	cells := make([]models.Cell, len(groups))
+	for i, group := range groups {
+		cells[i] = group.Cell
+	}
+	return cells, nil
+}
+
+func (pr *pixelRepository) RestorePixels(ctx context.Context, pixels []models.Pixel) error {
+	if len(pixels) == 0 {
+		return nil
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(pixels))
+	for _, pixel := range pixels {
+		filter := bson.M{"x": pixel.X, "y": pixel.Y}
+		if pixel.Color == "" {
+			writes = append(writes, mongo.NewDeleteManyModel().SetFilter(filter))
+			continue
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(filter).
+			SetUpdate(bson.M{"$set": pixel}).
+			SetUpsert(true))
+	}
+	_, err := pr.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+func (pr *pixelRepository) InsertPixelEvents(ctx context.Context, events []models.PixelEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	documents := make([]interface{}, len(events))
+	for i := range events {
+		documents[i] = events[i]
+	}
+	_, err := pr.eventsCollection.InsertMany(ctx, documents, options.InsertMany().SetOrdered(false))
 	return err
 }
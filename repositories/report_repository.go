@@ -0,0 +1,128 @@
+// repositories/report_repository.go
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"your_project/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ReportRepository interface {
+	CreateReport(ctx context.Context, report *models.Report) error
+	GetReport(ctx context.Context, id primitive.ObjectID) (*models.Report, error)
+	// GetReports возвращает очередь от старых жалоб к новым, без миниатюр
+	GetReports(ctx context.Context, query models.ReportQuery) ([]models.Report, error)
+	CountOpenReports(ctx context.Context, reporter string) (int64, error)
+	// AssignReport и ResolveReport меняют только открытую жалобу,
+	// для закрытой или несуществующей вернут mongo.ErrNoDocuments
+	AssignReport(ctx context.Context, id primitive.ObjectID, assignee string, at time.Time) (*models.Report, error)
+	ResolveReport(ctx context.Context, id primitive.ObjectID, status models.ReportStatus, resolvedBy, resolution string, at time.Time) (*models.Report, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+type reportRepository struct {
+	collection *mongo.Collection
+}
+
+func NewReportRepository(db *mongo.Database) ReportRepository {
+	return &reportRepository{
+		collection: db.Collection("reports"),
+	}
+}
+
+func (rr *reportRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := rr.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "createdAt", Value: 1}}},
+		{Keys: bson.D{{Key: "reporter", Value: 1}, {Key: "status", Value: 1}}},
+	})
+	return err
+}
+
+func (rr *reportRepository) CreateReport(ctx context.Context, report *models.Report) error {
+	result, err := rr.collection.InsertOne(ctx, report)
+	if err != nil {
+		return err
+	}
+	report.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (rr *reportRepository) GetReport(ctx context.Context, id primitive.ObjectID) (*models.Report, error) {
+	var report models.Report
+	if err := rr.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (rr *reportRepository) GetReports(ctx context.Context, query models.ReportQuery) ([]models.Report, error) {
+	filter := bson.M{}
+	if query.Status != "" {
+		filter["status"] = query.Status
+	}
+	if query.AssignedTo != "" {
+		filter["assignedTo"] = query.AssignedTo
+	}
+
+	opts := options.Find().
+		SetProjection(bson.M{"thumbnail": 0}).
+		SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}).
+		SetSkip(int64(query.Offset)).
+		SetLimit(int64(query.Limit))
+	cursor, err := rr.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var reports []models.Report
+	if err := cursor.All(ctx, &reports); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+func (rr *reportRepository) CountOpenReports(ctx context.Context, reporter string) (int64, error) {
+	return rr.collection.CountDocuments(ctx, bson.M{
+		"reporter": reporter,
+		"status":   models.ReportOpen,
+	})
+}
+
+func (rr *reportRepository) AssignReport(ctx context.Context, id primitive.ObjectID, assignee string, at time.Time) (*models.Report, error) {
+	return rr.updateOpen(ctx, id, bson.M{"$set": bson.M{
+		"assignedTo": assignee,
+		"assignedAt": at,
+	}})
+}
+
+func (rr *reportRepository) ResolveReport(ctx context.Context, id primitive.ObjectID, status models.ReportStatus, resolvedBy, resolution string, at time.Time) (*models.Report, error) {
+	return rr.updateOpen(ctx, id, bson.M{"$set": bson.M{
+		"status":     status,
+		"resolvedBy": resolvedBy,
+		"resolvedAt": at,
+		"resolution": resolution,
+	}})
+}
+
+func (rr *reportRepository) updateOpen(ctx context.Context, id primitive.ObjectID, update bson.M) (*models.Report, error) {
+	filter := bson.M{
+		"_id":    id,
+		"status": models.ReportOpen,
+	}
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetProjection(bson.M{"thumbnail": 0})
+
+	var report models.Report
+	if err := rr.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
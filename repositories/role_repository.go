@@ -0,0 +1,68 @@
+// repositories/role_repository.go
+package repositories
+
+import (
+	"context"
+
+	"your_project/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RoleRepository interface {
+	GetRole(ctx context.Context, publicKey string) (*models.UserRole, error)
+	SetRole(ctx context.Context, role *models.UserRole) error
+	// InsertRole сохраняет роль, только если у кошелька ещё нет записи
+	InsertRole(ctx context.Context, role *models.UserRole) error
+	// GetPrivileged возвращает всех, у кого роль выше игрока
+	GetPrivileged(ctx context.Context) ([]models.UserRole, error)
+}
+
+type roleRepository struct {
+	collection *mongo.Collection
+}
+
+func NewRoleRepository(db *mongo.Database) RoleRepository {
+	return &roleRepository{
+		collection: db.Collection("roles"),
+	}
+}
+
+func (rr *roleRepository) GetRole(ctx context.Context, publicKey string) (*models.UserRole, error) {
+	var role models.UserRole
+	if err := rr.collection.FindOne(ctx, bson.M{"_id": publicKey}).Decode(&role); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (rr *roleRepository) SetRole(ctx context.Context, role *models.UserRole) error {
+	_, err := rr.collection.ReplaceOne(ctx, bson.M{"_id": role.PublicKey}, role, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (rr *roleRepository) InsertRole(ctx context.Context, role *models.UserRole) error {
+	_, err := rr.collection.UpdateOne(ctx,
+		bson.M{"_id": role.PublicKey},
+		bson.M{"$setOnInsert": role},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (rr *roleRepository) GetPrivileged(ctx context.Context) ([]models.UserRole, error) {
+	filter := bson.M{"role": bson.M{"$ne": models.RolePlayer}}
+	cursor, err := rr.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "role", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var roles []models.UserRole
+	if err := cursor.All(ctx, &roles); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
@@ -0,0 +1,207 @@
+// repositories/session_repository.go
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"your_project/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type SessionRepository interface {
+	SaveChallenge(ctx context.Context, challenge *models.Challenge) error
+	GetChallenge(ctx context.Context, nonce string) (*models.Challenge, error)
+	ConsumeChallenge(ctx context.Context, nonce string) error
+	CreateSession(ctx context.Context, session *models.Session) error
+	GetSession(ctx context.Context, id string) (*models.Session, error)
+	GetActiveSessions(ctx context.Context, publicKey string) ([]models.Session, error)
+	RevokeSession(ctx context.Context, publicKey string, id string) error
+	// RevokeAllSessions отзывает все активные сессии кошелька и возвращает их число
+	RevokeAllSessions(ctx context.Context, publicKey string) (int64, error)
+	RevokeToken(ctx context.Context, token *models.RevokedToken) error
+	IsTokenRevoked(ctx context.Context, id string) (bool, error)
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	GetRefreshToken(ctx context.Context, hash string) (*models.RefreshToken, error)
+	// UseRefreshToken помечает токен использованным; для уже использованного
+	// вернёт mongo.ErrNoDocuments
+	UseRefreshToken(ctx context.Context, hash string) error
+	EnsureIndexes(ctx context.Context) error
+}
+
+type sessionRepository struct {
+	challenges    *mongo.Collection
+	sessions      *mongo.Collection
+	revokedTokens *mongo.Collection
+	refreshTokens *mongo.Collection
+}
+
+func NewSessionRepository(db *mongo.Database) SessionRepository {
+	return &sessionRepository{
+		challenges:    db.Collection("auth_challenges"),
+		sessions:      db.Collection("sessions"),
+		revokedTokens: db.Collection("revoked_tokens"),
+		refreshTokens: db.Collection("refresh_tokens"),
+	}
+}
+
+// EnsureIndexes создаёт TTL-индексы: Mongo сама удаляет просроченные challenge,
+// сессии, refresh-токены и записи об отозванных токенах
+func (sr *sessionRepository) EnsureIndexes(ctx context.Context) error {
+	ttl := mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+	if _, err := sr.challenges.Indexes().CreateOne(ctx, ttl); err != nil {
+		return err
+	}
+	if _, err := sr.revokedTokens.Indexes().CreateOne(ctx, ttl); err != nil {
+		return err
+	}
+	if _, err := sr.refreshTokens.Indexes().CreateOne(ctx, ttl); err != nil {
+		return err
+	}
+
+	_, err := sr.sessions.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		ttl,
+		{Keys: bson.D{{Key: "publicKey", Value: 1}}},
+	})
+	return err
+}
+
+func (sr *sessionRepository) SaveChallenge(ctx context.Context, challenge *models.Challenge) error {
+	_, err := sr.challenges.InsertOne(ctx, challenge)
+	return err
+}
+
+func (sr *sessionRepository) GetChallenge(ctx context.Context, nonce string) (*models.Challenge, error) {
+	var challenge models.Challenge
+	if err := sr.challenges.FindOne(ctx, bson.M{"_id": nonce}).Decode(&challenge); err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// ConsumeChallenge удаляет challenge; повторный вызов вернёт mongo.ErrNoDocuments
+func (sr *sessionRepository) ConsumeChallenge(ctx context.Context, nonce string) error {
+	result, err := sr.challenges.DeleteOne(ctx, bson.M{"_id": nonce})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (sr *sessionRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	_, err := sr.sessions.InsertOne(ctx, session)
+	return err
+}
+
+func (sr *sessionRepository) GetSession(ctx context.Context, id string) (*models.Session, error) {
+	var session models.Session
+	if err := sr.sessions.FindOne(ctx, bson.M{"_id": id}).Decode(&session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (sr *sessionRepository) GetActiveSessions(ctx context.Context, publicKey string) ([]models.Session, error) {
+	filter := bson.M{
+		"publicKey": publicKey,
+		"revokedAt": bson.M{"$exists": false},
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}
+	cursor, err := sr.sessions.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var sessions []models.Session
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (sr *sessionRepository) RevokeSession(ctx context.Context, publicKey string, id string) error {
+	filter := bson.M{
+		"_id":       id,
+		"publicKey": publicKey,
+		"revokedAt": bson.M{"$exists": false},
+	}
+	update := bson.M{"$set": bson.M{"revokedAt": time.Now()}}
+	result, err := sr.sessions.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (sr *sessionRepository) RevokeAllSessions(ctx context.Context, publicKey string) (int64, error) {
+	filter := bson.M{
+		"publicKey": publicKey,
+		"revokedAt": bson.M{"$exists": false},
+	}
+	update := bson.M{"$set": bson.M{"revokedAt": time.Now()}}
+	result, err := sr.sessions.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// RevokeToken заносит токен в список отозванных; повторный отзыв ничего не меняет
+func (sr *sessionRepository) RevokeToken(ctx context.Context, token *models.RevokedToken) error {
+	_, err := sr.revokedTokens.UpdateOne(ctx,
+		bson.M{"_id": token.ID},
+		bson.M{"$setOnInsert": token},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (sr *sessionRepository) IsTokenRevoked(ctx context.Context, id string) (bool, error) {
+	count, err := sr.revokedTokens.CountDocuments(ctx, bson.M{"_id": id}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (sr *sessionRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	_, err := sr.refreshTokens.InsertOne(ctx, token)
+	return err
+}
+
+func (sr *sessionRepository) GetRefreshToken(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := sr.refreshTokens.FindOne(ctx, bson.M{"_id": hash}).Decode(&token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (sr *sessionRepository) UseRefreshToken(ctx context.Context, hash string) error {
+	filter := bson.M{
+		"_id":    hash,
+		"usedAt": bson.M{"$exists": false},
+	}
+	update := bson.M{"$set": bson.M{"usedAt": time.Now()}}
+	result, err := sr.refreshTokens.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
@@ -0,0 +1,316 @@
+// repositories/stats_repository.go
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"your_project/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// bucketRetention - корзины нужны только для окон не длиннее суток,
+// старые удаляет TTL-индекс
+const bucketRetention = 25 * time.Hour
+
+type StatsRepository interface {
+	// RecordPlacement увеличивает общий счётчик и корзину установок кошелька и его команды
+	RecordPlacement(ctx context.Context, publicKey, teamID string, at, bucket time.Time) error
+	// AdjustOwned прибавляет к счётчикам занятых клеток разницу по каждому ключу
+	AdjustOwned(ctx context.Context, scope models.StatsScope, deltas map[string]int64) error
+	// GetStats возвращает первые limit счётчиков по убыванию поля sortField
+	GetStats(ctx context.Context, scope models.StatsScope, sortField string, limit int) ([]models.PlacementStats, error)
+	GetStatsByKeys(ctx context.Context, scope models.StatsScope, keys []string) ([]models.PlacementStats, error)
+	// GetWindowCounts складывает корзины начиная с since по убыванию числа установок.
+	// keys == nil - по всем ключам, limit == 0 - без ограничения
+	GetWindowCounts(ctx context.Context, scope models.StatsScope, keys []string, since time.Time, limit int) ([]models.WindowCount, error)
+	// IsBuilt сообщает, завершался ли хоть один Rebuild
+	IsBuilt(ctx context.Context) (bool, error)
+	// Rebuild пересчитывает счётчики по истории установок и текущему холсту
+	// и отмечает завершение; прерванный пересчёт отметки не оставит
+	Rebuild(ctx context.Context, bucketSize time.Duration, since time.Time) error
+	EnsureIndexes(ctx context.Context) error
+}
+
+type statsRepository struct {
+	collection        *mongo.Collection
+	bucketsCollection *mongo.Collection
+	pixelsCollection  *mongo.Collection
+	eventsCollection  *mongo.Collection
+	metaCollection    *mongo.Collection
+}
+
+// statsBuiltID - документ в stats_meta, который пишет завершённый Rebuild
+const statsBuiltID = "placement_stats"
+
+func NewStatsRepository(db *mongo.Database) StatsRepository {
+	return &statsRepository{
+		collection:        db.Collection("placement_stats"),
+		bucketsCollection: db.Collection("placement_buckets"),
+		pixelsCollection:  db.Collection("pixels"),
+		eventsCollection:  db.Collection("pixel_events"),
+		metaCollection:    db.Collection("stats_meta"),
+	}
+}
+
+func (sr *statsRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := sr.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "scope", Value: 1}, {Key: "key", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "scope", Value: 1}, {Key: "total", Value: -1}}},
+		{Keys: bson.D{{Key: "scope", Value: 1}, {Key: "owned", Value: -1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = sr.bucketsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "scope", Value: 1}, {Key: "key", Value: 1}, {Key: "start", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "scope", Value: 1}, {Key: "start", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "start", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(bucketRetention / time.Second)),
+		},
+	})
+	return err
+}
+
+func (sr *statsRepository) RecordPlacement(ctx context.Context, publicKey, teamID string, at, bucket time.Time) error {
+	var stats, buckets []mongo.WriteModel
+	record := func(scope models.StatsScope, key string) {
+		stats = append(stats, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"scope": scope, "key": key}).
+			SetUpdate(bson.M{
+				"$inc": bson.M{"total": 1},
+				"$set": bson.M{"teamId": teamID},
+				"$max": bson.M{"lastPlacedAt": at},
+			}).
+			SetUpsert(true))
+		buckets = append(buckets, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"scope": scope, "key": key, "start": bucket}).
+			SetUpdate(bson.M{"$inc": bson.M{"count": 1}}).
+			SetUpsert(true))
+	}
+	record(models.StatsWallet, publicKey)
+	if teamID != "" {
+		record(models.StatsTeam, teamID)
+	}
+
+	if _, err := sr.collection.BulkWrite(ctx, stats, options.BulkWrite().SetOrdered(false)); err != nil {
+		return err
+	}
+	_, err := sr.bucketsCollection.BulkWrite(ctx, buckets, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+func (sr *statsRepository) AdjustOwned(ctx context.Context, scope models.StatsScope, deltas map[string]int64) error {
+	writes := make([]mongo.WriteModel, 0, len(deltas))
+	for key, delta := range deltas {
+		if key == "" || delta == 0 {
+			continue
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"scope": scope, "key": key}).
+			SetUpdate(bson.M{"$inc": bson.M{"owned": delta}}).
+			SetUpsert(true))
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+	_, err := sr.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+func (sr *statsRepository) GetStats(ctx context.Context, scope models.StatsScope, sortField string, limit int) ([]models.PlacementStats, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: sortField, Value: -1}, {Key: "key", Value: 1}}).
+		SetLimit(int64(limit))
+	return sr.findStats(ctx, bson.M{"scope": scope}, opts)
+}
+
+func (sr *statsRepository) GetStatsByKeys(ctx context.Context, scope models.StatsScope, keys []string) ([]models.PlacementStats, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return sr.findStats(ctx, bson.M{"scope": scope, "key": bson.M{"$in": keys}}, options.Find())
+}
+
+func (sr *statsRepository) findStats(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.PlacementStats, error) {
+	cursor, err := sr.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var stats []models.PlacementStats
+	if err := cursor.All(ctx, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (sr *statsRepository) GetWindowCounts(ctx context.Context, scope models.StatsScope, keys []string, since time.Time, limit int) ([]models.WindowCount, error) {
+	match := bson.M{"scope": scope, "start": bson.M{"$gte": since}}
+	if keys != nil {
+		match["key"] = bson.M{"$in": keys}
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$key"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: "$count"}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+	if limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: limit}})
+	}
+
+	cursor, err := sr.bucketsCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var counts []models.WindowCount
+	if err := cursor.All(ctx, &counts); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+func (sr *statsRepository) IsBuilt(ctx context.Context) (bool, error) {
+	count, err := sr.metaCollection.CountDocuments(ctx, bson.M{"_id": statsBuiltID})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Rebuild считает всё на стороне MongoDB и сливает результат в коллекции
+// счётчиков через $merge. События откатов установками не считаются.
+// $merge перезаписывает поля, а не прибавляет, поэтому установки, сделанные
+// во время пересчёта, не задваиваются; пришедшие между чтением истории и
+// записью $merge могут потеряться до следующего пересчёта
+func (sr *statsRepository) Rebuild(ctx context.Context, bucketSize time.Duration, since time.Time) error {
+	// Снимаем отметку первой: если пересчёт прервётся, при следующем старте он повторится
+	if _, err := sr.metaCollection.DeleteOne(ctx, bson.M{"_id": statsBuiltID}); err != nil {
+		return err
+	}
+	// Ключи, которых нет в истории или на холсте, должны остаться с нулями
+	if _, err := sr.collection.UpdateMany(ctx, bson.D{}, bson.M{"$set": bson.M{"total": 0, "owned": 0}}); err != nil {
+		return err
+	}
+	if _, err := sr.bucketsCollection.DeleteMany(ctx, bson.D{}); err != nil {
+		return err
+	}
+
+	for _, scope := range []models.StatsScope{models.StatsWallet, models.StatsTeam} {
+		field := "publicKey"
+		if scope == models.StatsTeam {
+			field = "teamId"
+		}
+		present := bson.M{field: bson.M{"$nin": bson.A{"", nil}}}
+
+		totals := mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{"$and": bson.A{present, bson.M{"rollbackBy": bson.M{"$exists": false}}}}}},
+			{{Key: "$sort", Value: bson.D{{Key: "placedAt", Value: 1}}}},
+			{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: "$" + field},
+				{Key: "total", Value: bson.D{{Key: "$sum", Value: 1}}},
+				{Key: "teamId", Value: bson.D{{Key: "$last", Value: "$teamId"}}},
+				{Key: "lastPlacedAt", Value: bson.D{{Key: "$max", Value: "$placedAt"}}},
+			}}},
+			{{Key: "$project", Value: bson.D{
+				{Key: "_id", Value: 0},
+				{Key: "scope", Value: bson.D{{Key: "$literal", Value: scope}}},
+				{Key: "key", Value: "$_id"},
+				{Key: "teamId", Value: 1},
+				{Key: "total", Value: 1},
+				{Key: "lastPlacedAt", Value: 1},
+			}}},
+			mergeStage(sr.collection.Name(), "scope", "key"),
+		}
+		if err := runMerge(ctx, sr.eventsCollection, totals); err != nil {
+			return err
+		}
+
+		owned := mongo.Pipeline{
+			{{Key: "$match", Value: present}},
+			{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: "$" + field},
+				{Key: "owned", Value: bson.D{{Key: "$sum", Value: 1}}},
+			}}},
+			{{Key: "$project", Value: bson.D{
+				{Key: "_id", Value: 0},
+				{Key: "scope", Value: bson.D{{Key: "$literal", Value: scope}}},
+				{Key: "key", Value: "$_id"},
+				{Key: "owned", Value: 1},
+			}}},
+			mergeStage(sr.collection.Name(), "scope", "key"),
+		}
+		if err := runMerge(ctx, sr.pixelsCollection, owned); err != nil {
+			return err
+		}
+
+		// Начало корзины: время установки, округлённое вниз до bucketSize
+		placedAt := bson.D{{Key: "$toLong", Value: "$placedAt"}}
+		start := bson.D{{Key: "$toDate", Value: bson.D{{Key: "$subtract", Value: bson.A{
+			placedAt,
+			bson.D{{Key: "$mod", Value: bson.A{placedAt, bucketSize.Milliseconds()}}},
+		}}}}}
+		buckets := mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{"$and": bson.A{present, bson.M{
+				"rollbackBy": bson.M{"$exists": false},
+				"placedAt":   bson.M{"$gte": since},
+			}}}}},
+			{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: bson.D{{Key: "key", Value: "$" + field}, {Key: "start", Value: start}}},
+				{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			}}},
+			{{Key: "$project", Value: bson.D{
+				{Key: "_id", Value: 0},
+				{Key: "scope", Value: bson.D{{Key: "$literal", Value: scope}}},
+				{Key: "key", Value: "$_id.key"},
+				{Key: "start", Value: "$_id.start"},
+				{Key: "count", Value: 1},
+			}}},
+			mergeStage(sr.bucketsCollection.Name(), "scope", "key", "start"),
+		}
+		if err := runMerge(ctx, sr.eventsCollection, buckets); err != nil {
+			return err
+		}
+	}
+
+	_, err := sr.metaCollection.UpdateOne(ctx,
+		bson.M{"_id": statsBuiltID},
+		bson.M{"$set": bson.M{"builtAt": time.Now().UTC()}},
+		options.Update().SetUpsert(true))
+	return err
+}
+
+// mergeStage сливает документы в коллекцию по уникальному ключу on
+func mergeStage(into string, on ...string) bson.D {
+	return bson.D{{Key: "$merge", Value: bson.D{
+		{Key: "into", Value: into},
+		{Key: "on", Value: on},
+		{Key: "whenMatched", Value: "merge"},
+		{Key: "whenNotMatched", Value: "insert"},
+	}}}
+}
+
+func runMerge(ctx context.Context, collection *mongo.Collection, pipeline mongo.Pipeline) error {
+	cursor, err := collection.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return err
+	}
+	return cursor.Close(ctx)
+}
@@ -0,0 +1,115 @@
+// repositories/territory_repository.go
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"your_project/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type TerritoryRepository interface {
+	CreateTerritory(ctx context.Context, territory *models.Territory) error
+	GetTerritory(ctx context.Context, id primitive.ObjectID) (*models.Territory, error)
+	GetTerritories(ctx context.Context, query models.TerritoryQuery) ([]models.Territory, error)
+	// GetClaimed возвращает ждущие и действующие заявки всех команд
+	GetClaimed(ctx context.Context) ([]models.Territory, error)
+	// SetStatus переводит заявку в status, только если сейчас она в одном из from;
+	// иначе вернёт mongo.ErrNoDocuments
+	SetStatus(ctx context.Context, id primitive.ObjectID, from []models.TerritoryStatus, status models.TerritoryStatus, reviewedBy, note string, at time.Time) (*models.Territory, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+type territoryRepository struct {
+	collection *mongo.Collection
+}
+
+func NewTerritoryRepository(db *mongo.Database) TerritoryRepository {
+	return &territoryRepository{
+		collection: db.Collection("territories"),
+	}
+}
+
+func (tr *territoryRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := tr.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}}},
+		{Keys: bson.D{{Key: "teamId", Value: 1}, {Key: "status", Value: 1}}},
+	})
+	return err
+}
+
+func (tr *territoryRepository) CreateTerritory(ctx context.Context, territory *models.Territory) error {
+	result, err := tr.collection.InsertOne(ctx, territory)
+	if err != nil {
+		return err
+	}
+	territory.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (tr *territoryRepository) GetTerritory(ctx context.Context, id primitive.ObjectID) (*models.Territory, error) {
+	var territory models.Territory
+	if err := tr.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&territory); err != nil {
+		return nil, err
+	}
+	return &territory, nil
+}
+
+func (tr *territoryRepository) GetTerritories(ctx context.Context, query models.TerritoryQuery) ([]models.Territory, error) {
+	filter := bson.M{}
+	if query.TeamID != "" {
+		filter["teamId"] = query.TeamID
+	}
+	if query.Status != "" {
+		filter["status"] = query.Status
+	}
+	return tr.find(ctx, filter)
+}
+
+func (tr *territoryRepository) GetClaimed(ctx context.Context) ([]models.Territory, error) {
+	return tr.find(ctx, bson.M{
+		"status": bson.M{"$in": bson.A{models.TerritoryPending, models.TerritoryApproved}},
+	})
+}
+
+func (tr *territoryRepository) find(ctx context.Context, filter bson.M) ([]models.Territory, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "requestedAt", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := tr.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var territories []models.Territory
+	if err := cursor.All(ctx, &territories); err != nil {
+		return nil, err
+	}
+	return territories, nil
+}
+
+func (tr *territoryRepository) SetStatus(ctx context.Context, id primitive.ObjectID, from []models.TerritoryStatus, status models.TerritoryStatus, reviewedBy, note string, at time.Time) (*models.Territory, error) {
+	filter := bson.M{
+		"_id":    id,
+		"status": bson.M{"$in": from},
+	}
+	update := bson.M{"$set": bson.M{
+		"status":     status,
+		"reviewedBy": reviewedBy,
+		"reviewedAt": at,
+		"reviewNote": note,
+	}}
+
+	var territory models.Territory
+	err := tr.collection.FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&territory)
+	if err != nil {
+		return nil, err
+	}
+	return &territory, nil
+}
@@ -0,0 +1,76 @@
+// repositories/zone_repository.go
+package repositories
+
+import (
+	"context"
+
+	"your_project/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ZoneRepository interface {
+	GetZones(ctx context.Context) ([]models.Zone, error)
+	CreateZone(ctx context.Context, zone *models.Zone) error
+	// ReplaceZone и DeleteZone для несуществующей зоны вернут mongo.ErrNoDocuments
+	ReplaceZone(ctx context.Context, zone *models.Zone) error
+	DeleteZone(ctx context.Context, id primitive.ObjectID) error
+}
+
+type zoneRepository struct {
+	collection *mongo.Collection
+}
+
+func NewZoneRepository(db *mongo.Database) ZoneRepository {
+	return &zoneRepository{
+		collection: db.Collection("zones"),
+	}
+}
+
+func (zr *zoneRepository) GetZones(ctx context.Context) ([]models.Zone, error) {
+	cursor, err := zr.collection.Find(ctx, bson.D{}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var zones []models.Zone
+	if err := cursor.All(ctx, &zones); err != nil {
+		return nil, err
+	}
+	return zones, nil
+}
+
+func (zr *zoneRepository) CreateZone(ctx context.Context, zone *models.Zone) error {
+	result, err := zr.collection.InsertOne(ctx, zone)
+	if err != nil {
+		return err
+	}
+	zone.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (zr *zoneRepository) ReplaceZone(ctx context.Context, zone *models.Zone) error {
+	result, err := zr.collection.ReplaceOne(ctx, bson.M{"_id": zone.ID}, zone)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (zr *zoneRepository) DeleteZone(ctx context.Context, id primitive.ObjectID) error {
+	result, err := zr.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+// services/api_key_service.go
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"your_project/models"
+	"your_project/repositories"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// apiKeyPrefix отличает ключи от JWT и помогает находить их в утёкших логах
+	apiKeyPrefix = "pxk_"
+	// maxAPIKeysPerWallet - сколько активных ключей может быть у одного кошелька
+	maxAPIKeysPerWallet = 10
+	// apiKeyTouchInterval - не чаще этого обновляем lastUsedAt, чтобы не писать в базу на каждый запрос
+	apiKeyTouchInterval = time.Minute
+)
+
+var (
+	ErrInvalidAPIKey  = errors.New("invalid API key")
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	ErrInvalidScope   = errors.New("unknown API key scope")
+	ErrInvalidKeyName = errors.New("API key name must be 1-64 characters")
+	ErrTooManyAPIKeys = errors.New("too many API keys")
+)
+
+var (
+	validAPIKeyScopes = []string{models.ScopePixelsWrite, models.ScopeTeamsWrite}
+	// defaultAPIKeyScopes - права ключа, если при создании они не указаны
+	defaultAPIKeyScopes = []string{models.ScopePixelsWrite}
+)
+
+type APIKeyService interface {
+	// CreateKey выпускает ключ и возвращает его в открытом виде. Повторно получить его нельзя
+	CreateKey(ctx context.Context, publicKey, name string, scopes []string) (*models.APIKey, string, error)
+	GetKeys(ctx context.Context, publicKey string) ([]models.APIKey, error)
+	RevokeKey(ctx context.Context, publicKey, id string) error
+	// AuthenticateAPIKey находит активный ключ по его значению
+	AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error)
+}
+
+type apiKeyService struct {
+	repository repositories.APIKeyRepository
+}
+
+func NewAPIKeyService(repo repositories.APIKeyRepository) APIKeyService {
+	return &apiKeyService{
+		repository: repo,
+	}
+}
+
+func (ks *apiKeyService) CreateKey(ctx context.Context, publicKey, name string, scopes []string) (*models.APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 64 {
+		return nil, "", ErrInvalidKeyName
+	}
+	if len(scopes) == 0 {
+		scopes = defaultAPIKeyScopes
+	}
+	for _, scope := range scopes {
+		if !containsString(validAPIKeyScopes, scope) {
+			return nil, "", ErrInvalidScope
+		}
+	}
+
+	count, err := ks.repository.CountKeys(ctx, publicKey)
+	if err != nil {
+		return nil, "", err
+	}
+	if count >= maxAPIKeysPerWallet {
+		return nil, "", ErrTooManyAPIKeys
+	}
+
+	id, err := generateNonce()
+	if err != nil {
+		return nil, "", err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", err
+	}
+	raw := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	key := &models.APIKey{
+		ID:        id,
+		PublicKey: publicKey,
+		Name:      name,
+		Prefix:    raw[:len(apiKeyPrefix)+6],
+		Hash:      hashAPIKey(raw),
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := ks.repository.CreateKey(ctx, key); err != nil {
+		return nil, "", err
+	}
+	return key, raw, nil
+}
+
+func (ks *apiKeyService) GetKeys(ctx context.Context, publicKey string) ([]models.APIKey, error) {
+	keys, err := ks.repository.GetKeys(ctx, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	if keys == nil {
+		keys = []models.APIKey{}
+	}
+	return keys, nil
+}
+
+func (ks *apiKeyService) RevokeKey(ctx context.Context, publicKey, id string) error {
+	err := ks.repository.RevokeKey(ctx, publicKey, id)
+	if err == mongo.ErrNoDocuments {
+		return ErrAPIKeyNotFound
+	}
+	return err
+}
+
+func (ks *apiKeyService) AuthenticateAPIKey(ctx context.Context, raw string) (*models.APIKey, error) {
+	if !strings.HasPrefix(raw, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+	key, err := ks.repository.GetKeyByHash(ctx, hashAPIKey(raw))
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now().UTC()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyTouchInterval {
+		// Отметка об использовании не критична: ошибку не показываем клиенту
+		if err := ks.repository.TouchKey(ctx, key.ID, now); err == nil {
+			key.LastUsedAt = &now
+		}
+	}
+	return key, nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
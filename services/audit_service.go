@@ -0,0 +1,95 @@
+// services/audit_service.go
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"your_project/models"
+	"your_project/repositories"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+var ErrInvalidAuditQuery = errors.New("invalid audit query")
+
+// AuditService ведёт журнал действий модераторов и админов
+type AuditService interface {
+	Record(ctx context.Context, entry *models.AuditEntry) error
+	GetEntries(ctx context.Context, query models.AuditQuery) (*models.AuditPage, error)
+	// Export передаёт fn все подходящие записи от старых к новым; Limit и Offset не учитываются
+	Export(ctx context.Context, query models.AuditQuery, fn func(*models.AuditEntry) error) error
+}
+
+type auditService struct {
+	repository repositories.AuditRepository
+}
+
+func NewAuditService(repo repositories.AuditRepository) AuditService {
+	return &auditService{
+		repository: repo,
+	}
+}
+
+func (as *auditService) Record(ctx context.Context, entry *models.AuditEntry) error {
+	entry.CreatedAt = time.Now().UTC()
+	return as.repository.InsertEntry(ctx, entry)
+}
+
+func (as *auditService) GetEntries(ctx context.Context, query models.AuditQuery) (*models.AuditPage, error) {
+	if err := validateAuditQuery(query); err != nil {
+		return nil, err
+	}
+	if query.Limit < 0 || query.Offset < 0 {
+		return nil, ErrInvalidAuditQuery
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultAuditLimit
+	}
+	if query.Limit > maxAuditLimit {
+		query.Limit = maxAuditLimit
+	}
+
+	// Запрашиваем на одну запись больше, чтобы понять, есть ли следующая страница
+	limit := query.Limit
+	query.Limit++
+	entries, err := as.repository.GetEntries(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.AuditPage{
+		Entries: entries,
+		Limit:   limit,
+		Offset:  query.Offset,
+	}
+	if len(entries) > limit {
+		page.Entries = entries[:limit]
+		page.HasMore = true
+	}
+	if page.Entries == nil {
+		page.Entries = []models.AuditEntry{}
+	}
+	return page, nil
+}
+
+func (as *auditService) Export(ctx context.Context, query models.AuditQuery, fn func(*models.AuditEntry) error) error {
+	if err := validateAuditQuery(query); err != nil {
+		return err
+	}
+	return as.repository.EachEntry(ctx, query, fn)
+}
+
+func validateAuditQuery(query models.AuditQuery) error {
+	if query.TargetType != "" && !query.TargetType.Valid() {
+		return ErrInvalidAuditQuery
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return ErrInvalidAuditQuery
+	}
+	return nil
+}
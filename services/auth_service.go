@@ -0,0 +1,387 @@
+// services/auth_service.go
+package services
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"your_project/config"
+	"your_project/models"
+	"your_project/repositories"
+
+	"github.com/blocto/solana-go-sdk/common"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrInvalidPublicKey  = errors.New("invalid public key")
+	ErrChallengeNotFound = errors.New("challenge not found")
+	ErrSessionNotFound   = errors.New("session not found")
+	ErrSessionRevoked    = errors.New("session revoked or expired")
+	ErrTokenRevoked      = errors.New("token revoked")
+	// ErrInvalidRefreshToken - токен неизвестен, истёк или его сессия закрыта
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrChallengeExpired    = errors.New("challenge expired")
+	ErrMessageMismatch     = errors.New("signed message does not match the challenge")
+	ErrInvalidSignature    = errors.New("invalid signature")
+)
+
+type AuthService interface {
+	IssueChallenge(ctx context.Context, publicKey string) (*models.Challenge, error)
+	// SignIn проверяет подпись сообщения, выданного IssueChallenge, одноразово
+	// погашает его nonce и открывает новую сессию с первым refresh-токеном
+	SignIn(ctx context.Context, req SignInRequest) (*SessionTokens, error)
+	// Refresh обменивает refresh-токен на следующий. Повторно предъявленный
+	// токен означает утечку: сессия отзывается целиком (*TokenReuseError)
+	Refresh(ctx context.Context, refreshToken string) (*SessionTokens, error)
+	// SessionByRefreshToken находит сессию токена, например чтобы закрыть её при выходе
+	SessionByRefreshToken(ctx context.Context, refreshToken string) (*models.Session, error)
+	ValidateSession(ctx context.Context, sessionID, publicKey string) error
+	// ValidateToken проверяет, что токен не отозван и его сессия жива
+	ValidateToken(ctx context.Context, tokenID, sessionID, publicKey string) error
+	GetSessions(ctx context.Context, publicKey string) ([]models.Session, error)
+	RevokeSession(ctx context.Context, publicKey, sessionID string) error
+	// RevokeAllSessions - выход со всех устройств
+	RevokeAllSessions(ctx context.Context, publicKey string) (int64, error)
+	// Logout отзывает токен и закрывает его сессию
+	Logout(ctx context.Context, token RevokeTokenRequest) error
+}
+
+type RevokeTokenRequest struct {
+	TokenID   string
+	SessionID string
+	PublicKey string
+	ExpiresAt time.Time
+}
+
+// SessionTokens - сессия и новый refresh-токен к ней (в открытом виде, в базе только хэш).
+// Role - текущая роль кошелька для access-токена
+type SessionTokens struct {
+	Session      *models.Session
+	RefreshToken string
+	Role         models.Role
+}
+
+// TokenReuseError - предъявлен уже использованный refresh-токен, сессия отозвана
+type TokenReuseError struct {
+	PublicKey string
+	SessionID string
+}
+
+func (e *TokenReuseError) Error() string {
+	return "refresh token reused, session revoked"
+}
+
+type SignInRequest struct {
+	PublicKey string
+	Message   string
+	Signature []byte
+	UserAgent string
+	IPAddress string
+}
+
+type authService struct {
+	repository repositories.SessionRepository
+	roles      RoleService
+	config     *config.Config
+}
+
+func NewAuthService(repo repositories.SessionRepository, roles RoleService, cfg *config.Config) AuthService {
+	return &authService{
+		repository: repo,
+		roles:      roles,
+		config:     cfg,
+	}
+}
+
+func (as *authService) IssueChallenge(ctx context.Context, publicKey string) (*models.Challenge, error) {
+	if !isValidPublicKey(publicKey) {
+		return nil, ErrInvalidPublicKey
+	}
+
+	nonce, err := generateNonce()
+	if err != nil {
+		return nil, err
+	}
+
+	issuedAt := time.Now().UTC().Truncate(time.Second)
+	challenge := &models.Challenge{
+		Nonce:     nonce,
+		PublicKey: publicKey,
+		IssuedAt:  issuedAt,
+		ExpiresAt: issuedAt.Add(as.config.ChallengeTTL),
+	}
+	challenge.Message = buildSignInMessage(as.config.AuthDomain, challenge)
+
+	if err := as.repository.SaveChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+func (as *authService) SignIn(ctx context.Context, req SignInRequest) (*SessionTokens, error) {
+	if err := as.verifyChallenge(ctx, req.PublicKey, req.Message, req.Signature); err != nil {
+		return nil, err
+	}
+
+	id, err := generateNonce()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	session := &models.Session{
+		ID:        id,
+		PublicKey: req.PublicKey,
+		CreatedAt: now,
+		ExpiresAt: now.Add(as.config.SessionTTL),
+		UserAgent: req.UserAgent,
+		IPAddress: req.IPAddress,
+	}
+	if err := as.repository.CreateSession(ctx, session); err != nil {
+		return nil, err
+	}
+	return as.issueRefreshToken(ctx, session)
+}
+
+func (as *authService) Refresh(ctx context.Context, refreshToken string) (*SessionTokens, error) {
+	hash := hashRefreshToken(refreshToken)
+	stored, err := as.repository.GetRefreshToken(ctx, hash)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	if stored.UsedAt != nil {
+		return nil, as.revokeFamily(ctx, stored)
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	session, err := as.repository.GetSession(ctx, stored.SessionID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	if !session.Active(time.Now()) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Из двух одновременных обменов одного токена пройдёт один, второй считается повтором
+	if err := as.repository.UseRefreshToken(ctx, hash); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, as.revokeFamily(ctx, stored)
+		}
+		return nil, err
+	}
+	return as.issueRefreshToken(ctx, session)
+}
+
+func (as *authService) SessionByRefreshToken(ctx context.Context, refreshToken string) (*models.Session, error) {
+	stored, err := as.repository.GetRefreshToken(ctx, hashRefreshToken(refreshToken))
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	session, err := as.repository.GetSession(ctx, stored.SessionID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+// revokeFamily отзывает сессию, к которой относится повторно использованный токен
+func (as *authService) revokeFamily(ctx context.Context, token *models.RefreshToken) error {
+	err := as.repository.RevokeSession(ctx, token.PublicKey, token.SessionID)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return err
+	}
+	return &TokenReuseError{PublicKey: token.PublicKey, SessionID: token.SessionID}
+}
+
+// issueRefreshToken выпускает следующий refresh-токен сессии. Роль читается
+// заново, поэтому её смена попадает в токены при ближайшем обновлении
+func (as *authService) issueRefreshToken(ctx context.Context, session *models.Session) (*SessionTokens, error) {
+	role, err := as.roles.RoleOf(ctx, session.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	raw := base64.RawURLEncoding.EncodeToString(b)
+
+	err = as.repository.CreateRefreshToken(ctx, &models.RefreshToken{
+		Hash:      hashRefreshToken(raw),
+		SessionID: session.ID,
+		PublicKey: session.PublicKey,
+		CreatedAt: time.Now().UTC(),
+		ExpiresAt: session.ExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &SessionTokens{Session: session, RefreshToken: raw, Role: role}, nil
+}
+
+func (as *authService) verifyChallenge(ctx context.Context, publicKey, message string, signature []byte) error {
+	nonce, ok := nonceFromMessage(message)
+	if !ok {
+		return ErrMessageMismatch
+	}
+
+	challenge, err := as.repository.GetChallenge(ctx, nonce)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return ErrChallengeNotFound
+		}
+		return err
+	}
+	if time.Now().After(challenge.ExpiresAt) {
+		return ErrChallengeExpired
+	}
+	// Подписанное сообщение должно совпадать с выданным байт в байт
+	if challenge.PublicKey != publicKey || challenge.Message != message {
+		return ErrMessageMismatch
+	}
+
+	pubKeyBytes := common.PublicKeyFromString(publicKey).Bytes()
+	if !ed25519.Verify(pubKeyBytes, []byte(message), signature) {
+		return ErrInvalidSignature
+	}
+
+	// Погашаем nonce только после успешной проверки: из двух одновременных
+	// запросов с одной подписью пройдёт один
+	if err := as.repository.ConsumeChallenge(ctx, nonce); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return ErrChallengeNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (as *authService) ValidateSession(ctx context.Context, sessionID, publicKey string) error {
+	session, err := as.repository.GetSession(ctx, sessionID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return ErrSessionRevoked
+		}
+		return err
+	}
+	if session.PublicKey != publicKey || !session.Active(time.Now()) {
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+func (as *authService) ValidateToken(ctx context.Context, tokenID, sessionID, publicKey string) error {
+	revoked, err := as.repository.IsTokenRevoked(ctx, tokenID)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return ErrTokenRevoked
+	}
+	return as.ValidateSession(ctx, sessionID, publicKey)
+}
+
+func (as *authService) GetSessions(ctx context.Context, publicKey string) ([]models.Session, error) {
+	sessions, err := as.repository.GetActiveSessions(ctx, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	if sessions == nil {
+		sessions = []models.Session{}
+	}
+	return sessions, nil
+}
+
+func (as *authService) RevokeSession(ctx context.Context, publicKey, sessionID string) error {
+	err := as.repository.RevokeSession(ctx, publicKey, sessionID)
+	if err == mongo.ErrNoDocuments {
+		return ErrSessionNotFound
+	}
+	return err
+}
+
+func (as *authService) RevokeAllSessions(ctx context.Context, publicKey string) (int64, error) {
+	return as.repository.RevokeAllSessions(ctx, publicKey)
+}
+
+func (as *authService) Logout(ctx context.Context, token RevokeTokenRequest) error {
+	err := as.repository.RevokeToken(ctx, &models.RevokedToken{
+		ID:        token.TokenID,
+		PublicKey: token.PublicKey,
+		SessionID: token.SessionID,
+		RevokedAt: time.Now(),
+		ExpiresAt: token.ExpiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Сессия могла быть уже отозвана с другого устройства
+	err = as.repository.RevokeSession(ctx, token.PublicKey, token.SessionID)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	return err
+}
+
+// buildSignInMessage формирует сообщение в духе Sign-In With Solana
+func buildSignInMessage(domain string, c *models.Challenge) string {
+	return fmt.Sprintf("%s wants you to sign in with your Solana account:\n"+
+		"%s\n\n"+
+		"Sign in to the pixel canvas.\n\n"+
+		"Nonce: %s\n"+
+		"Issued At: %s\n"+
+		"Expiration Time: %s",
+		domain, c.PublicKey, c.Nonce,
+		c.IssuedAt.Format(time.RFC3339), c.ExpiresAt.Format(time.RFC3339))
+}
+
+func nonceFromMessage(message string) (string, bool) {
+	for _, line := range strings.Split(message, "\n") {
+		if nonce, ok := strings.CutPrefix(line, "Nonce: "); ok && nonce != "" {
+			return nonce, true
+		}
+	}
+	return "", false
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// isValidPublicKey проверяет, что строка - 32-байтовый ключ в base58
+func isValidPublicKey(publicKey string) bool {
+	return publicKey != "" && common.PublicKeyFromString(publicKey).ToBase58() == publicKey
+}
@@ -0,0 +1,213 @@
+// services/ban_service.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"your_project/models"
+	"your_project/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	defaultBanListLimit = 50
+	maxBanListLimit     = 500
+)
+
+var (
+	ErrBanNotFound    = errors.New("ban not found")
+	ErrInvalidBan     = errors.New("ban type must be placement or full, with a reason")
+	ErrCannotBanPeer  = errors.New("cannot ban a wallet with the same or a higher role")
+	ErrCannotLiftBan  = errors.New("cannot lift a ban on a wallet with the same or a higher role or one issued by a higher role")
+	ErrInvalidBanList = errors.New("invalid ban list query")
+)
+
+// BanError - действие запрещено баном
+type BanError struct {
+	Ban *models.Ban
+}
+
+func (e *BanError) Error() string {
+	if e.Ban.ExpiresAt == nil {
+		return fmt.Sprintf("wallet is banned: %s", e.Ban.Reason)
+	}
+	return fmt.Sprintf("wallet is banned until %s: %s", e.Ban.ExpiresAt.Format(time.RFC3339), e.Ban.Reason)
+}
+
+type BanRequest struct {
+	PublicKey string
+	Type      models.BanType
+	Reason    string
+	Duration  time.Duration // 0 - бессрочно
+	IssuedBy  string
+}
+
+type BanService interface {
+	Ban(ctx context.Context, req BanRequest) (*models.Ban, error)
+	LiftBan(ctx context.Context, id, liftedBy, reason string) (*models.Ban, error)
+	GetBans(ctx context.Context, query models.BanQuery) ([]models.Ban, error)
+	// CheckAccess возвращает *BanError, если у кошелька полный бан
+	CheckAccess(ctx context.Context, publicKey string) error
+	// CheckPlacement возвращает *BanError при любом действующем бане
+	CheckPlacement(ctx context.Context, publicKey string) error
+}
+
+type banService struct {
+	repository repositories.BanRepository
+	roles      RoleService
+}
+
+func NewBanService(repo repositories.BanRepository, roles RoleService) BanService {
+	return &banService{
+		repository: repo,
+		roles:      roles,
+	}
+}
+
+func (bs *banService) Ban(ctx context.Context, req BanRequest) (*models.Ban, error) {
+	req.Reason = strings.TrimSpace(req.Reason)
+	if (req.Type != models.BanPlacement && req.Type != models.BanFull) || req.Reason == "" || req.Duration < 0 {
+		return nil, ErrInvalidBan
+	}
+	if !isValidPublicKey(req.PublicKey) {
+		return nil, ErrInvalidPublicKey
+	}
+
+	// Модератор банит только игроков, админ - ещё и модераторов
+	actorRole, err := bs.roles.RoleOf(ctx, req.IssuedBy)
+	if err != nil {
+		return nil, err
+	}
+	targetRole, err := bs.roles.RoleOf(ctx, req.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if targetRole.AtLeast(actorRole) {
+		return nil, ErrCannotBanPeer
+	}
+
+	now := time.Now().UTC()
+	ban := &models.Ban{
+		PublicKey: req.PublicKey,
+		Type:      req.Type,
+		Reason:    req.Reason,
+		IssuedBy:  req.IssuedBy,
+		IssuedAt:  now,
+	}
+	if req.Duration > 0 {
+		expiresAt := now.Add(req.Duration)
+		ban.ExpiresAt = &expiresAt
+	}
+	if err := bs.repository.CreateBan(ctx, ban); err != nil {
+		return nil, err
+	}
+	return ban, nil
+}
+
+func (bs *banService) LiftBan(ctx context.Context, id, liftedBy, reason string) (*models.Ban, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrBanNotFound
+	}
+	existing, err := bs.repository.GetBan(ctx, objectID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrBanNotFound
+		}
+		return nil, err
+	}
+
+	// Как и в Ban: модератор снимает только баны игроков и только выданные
+	// модератором, бан от админа снимает админ
+	actorRole, err := bs.roles.RoleOf(ctx, liftedBy)
+	if err != nil {
+		return nil, err
+	}
+	targetRole, err := bs.roles.RoleOf(ctx, existing.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	issuerRole, err := bs.roles.RoleOf(ctx, existing.IssuedBy)
+	if err != nil {
+		return nil, err
+	}
+	if targetRole.AtLeast(actorRole) || !actorRole.AtLeast(issuerRole) {
+		return nil, ErrCannotLiftBan
+	}
+
+	ban, err := bs.repository.LiftBan(ctx, objectID, liftedBy, strings.TrimSpace(reason), time.Now().UTC())
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrBanNotFound
+		}
+		return nil, err
+	}
+	return ban, nil
+}
+
+func (bs *banService) GetBans(ctx context.Context, query models.BanQuery) ([]models.Ban, error) {
+	if query.Limit == 0 {
+		query.Limit = defaultBanListLimit
+	}
+	if query.Limit < 0 || query.Limit > maxBanListLimit || query.Offset < 0 {
+		return nil, ErrInvalidBanList
+	}
+
+	bans, err := bs.repository.GetBans(ctx, query, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if bans == nil {
+		bans = []models.Ban{}
+	}
+	return bans, nil
+}
+
+func (bs *banService) CheckAccess(ctx context.Context, publicKey string) error {
+	ban, err := bs.activeBan(ctx, publicKey)
+	if err != nil || ban == nil || ban.Type != models.BanFull {
+		return err
+	}
+	return &BanError{Ban: ban}
+}
+
+func (bs *banService) CheckPlacement(ctx context.Context, publicKey string) error {
+	ban, err := bs.activeBan(ctx, publicKey)
+	if err != nil || ban == nil {
+		return err
+	}
+	return &BanError{Ban: ban}
+}
+
+// activeBan выбирает самый строгий из действующих банов: полный важнее
+// бана на рисование, бессрочный или более долгий - короткого
+func (bs *banService) activeBan(ctx context.Context, publicKey string) (*models.Ban, error) {
+	bans, err := bs.repository.GetActiveBans(ctx, publicKey, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	var strongest *models.Ban
+	for i := range bans {
+		if strongest == nil || stricterBan(&bans[i], strongest) {
+			strongest = &bans[i]
+		}
+	}
+	return strongest, nil
+}
+
+func stricterBan(a, b *models.Ban) bool {
+	if a.Type != b.Type {
+		return a.Type == models.BanFull
+	}
+	if a.ExpiresAt == nil || b.ExpiresAt == nil {
+		return a.ExpiresAt == nil && b.ExpiresAt != nil
+	}
+	return a.ExpiresAt.After(*b.ExpiresAt)
+}
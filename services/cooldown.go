@@ -0,0 +1,112 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CooldownError возвращается, если кошелёк или его команда ещё не могут ставить пиксель
+type CooldownError struct {
+	Remaining time.Duration
+}
+
+func (e *CooldownError) Error() string {
+	return fmt.Sprintf("cooldown: %s remaining", e.Remaining.Round(time.Millisecond))
+}
+
+// cooldownTracker хранит время последней установки по ключу (кошелёк или команда)
+type cooldownTracker struct {
+	mutex     sync.Mutex
+	last      map[string]time.Time
+	sweepSize int
+	maxPeriod time.Duration // Самая длинная пауза из конфигурации
+}
+
+// newCooldownTracker создаёт трекер; записи старше maxPeriod считаются истёкшими
+// для любого ключа и удаляются при чистке
+func newCooldownTracker(maxPeriod time.Duration) *cooldownTracker {
+	return &cooldownTracker{
+		last:      make(map[string]time.Time),
+		sweepSize: 1024,
+		maxPeriod: maxPeriod,
+	}
+}
+
+type cooldownKey struct {
+	key    string
+	period time.Duration
+}
+
+// remaining возвращает максимальное оставшееся время ожидания по всем ключам
+func (t *cooldownTracker) remaining(now time.Time, keys ...cooldownKey) time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.remainingLocked(now, keys)
+}
+
+// reserve атомарно проверяет паузы и, если все истекли, отмечает установку
+func (t *cooldownTracker) reserve(now time.Time, keys ...cooldownKey) time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if remaining := t.remainingLocked(now, keys); remaining > 0 {
+		return remaining
+	}
+	for _, k := range keys {
+		if k.period > 0 && k.key != "" {
+			t.last[k.key] = now
+		}
+	}
+	t.sweepLocked(now)
+	return 0
+}
+
+// release отменяет сделанную в now резервацию, если установка не сохранилась.
+// Прежние паузы к моменту reserve уже истекли, поэтому записи просто удаляются
+func (t *cooldownTracker) release(now time.Time, keys ...cooldownKey) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, k := range keys {
+		// Запись могла смениться: тогда это чужая резервация
+		if last, ok := t.last[k.key]; ok && last.Equal(now) {
+			delete(t.last, k.key)
+		}
+	}
+}
+
+func (t *cooldownTracker) remainingLocked(now time.Time, keys []cooldownKey) time.Duration {
+	var remaining time.Duration
+	for _, k := range keys {
+		if k.period <= 0 || k.key == "" {
+			continue
+		}
+		last, ok := t.last[k.key]
+		if !ok {
+			continue
+		}
+		if r := last.Add(k.period).Sub(now); r > remaining {
+			remaining = r
+		}
+	}
+	return remaining
+}
+
+// sweepLocked удаляет давно истёкшие записи, чтобы карта не росла бесконечно.
+// Периоды у ключей разные (штраф территории, пауза команды), поэтому
+// ориентируемся на самый длинный из конфигурации, а не на ключи вызывающего
+func (t *cooldownTracker) sweepLocked(now time.Time) {
+	if len(t.last) < t.sweepSize {
+		return
+	}
+	for key, last := range t.last {
+		if now.Sub(last) > t.maxPeriod {
+			delete(t.last, key)
+		}
+	}
+	t.sweepSize = 2 * len(t.last)
+	if t.sweepSize < 1024 {
+		t.sweepSize = 1024
+	}
+}
@@ -2,30 +2,257 @@ package services
 
 import (
 	"context"
+	"errors"
+	"log"
+	"time"
 
+	"your_project/canvas"
+	"your_project/config"
 	"your_project/models"
 	"your_project/repositories"
 )
 
 type PixelService interface {
 	GetAllPixels(ctx context.Context) ([]models.Pixel, error)
-	UpsertPixel(ctx context.Context, pixel models.Pixel) error
+	GetPixel(ctx context.Context, x, y int) (*models.Pixel, error)
+	PlacePixel(ctx context.Context, publicKey string, pixel models.Pixel) (models.Pixel, error)
+	GetPixelHistory(ctx context.Context, query models.PixelHistoryQuery) (*models.PixelHistoryPage, error)
+	CooldownRemaining(ctx context.Context, publicKey string) (time.Duration, error)
+	Cooldown() time.Duration
+	Canvas() models.Canvas
+	Palette() *canvas.Palette
 }
 
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+)
+
+var (
+	ErrInvalidHistoryQuery = errors.New("invalid history query")
+	ErrAnonymousPlacement  = errors.New("placement requires an authenticated wallet")
+	ErrOutOfBounds         = errors.New("pixel is outside the canvas")
+	ErrInvalidColor        = errors.New("color is not in the palette")
+)
+
 type pixelService struct {
-	repository repositories.PixelRepository
+	repository     repositories.PixelRepository
+	teamRepository repositories.TeamRepository
+	bans           BanService
+	zones          ZoneService
+	territories    TerritoryService
+	stats          StatsService
+	config         *config.Config
+	palette        *canvas.Palette
+	cooldowns      *cooldownTracker
 }
 
-func NewPixelService(repo repositories.PixelRepository) PixelService {
+func NewPixelService(repo repositories.PixelRepository, teamRepo repositories.TeamRepository, bans BanService, zones ZoneService, territories TerritoryService, stats StatsService, cfg *config.Config, palette *canvas.Palette) PixelService {
 	return &pixelService{
-		repository: repo,
+		repository:     repo,
+		teamRepository: teamRepo,
+		bans:           bans,
+		zones:          zones,
+		territories:    territories,
+		stats:          stats,
+		config:         cfg,
+		palette:        palette,
+		cooldowns:      newCooldownTracker(max(cfg.PlacementCooldown+cfg.TerritoryPenalty, cfg.TeamPlacementCooldown)),
 	}
 }
 
 func (ps *pixelService) GetAllPixels(ctx context.Context) ([]models.Pixel, error) {
-	return ps.repository.GetAllPixels(ctx)
+	pixels, err := ps.repository.GetAllPixels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pixels {
+		ps.fillColorIndex(&pixels[i])
+	}
+	return pixels, nil
+}
+
+func (ps *pixelService) GetPixel(ctx context.Context, x, y int) (*models.Pixel, error) {
+	pixel, err := ps.repository.GetPixel(ctx, x, y)
+	if err != nil {
+		return nil, err
+	}
+	ps.fillColorIndex(pixel)
+	return pixel, nil
+}
+
+// PlacePixel сохраняет пиксель вместе с автором, его командой и временем установки.
+// Флаг Bot выставляет вызывающий; кулдаун у бота общий с кошельком-владельцем
+func (ps *pixelService) PlacePixel(ctx context.Context, publicKey string, pixel models.Pixel) (models.Pixel, error) {
+	if publicKey == "" {
+		return models.Pixel{}, ErrAnonymousPlacement
+	}
+	if !ps.Canvas().Contains(pixel.X, pixel.Y) {
+		return models.Pixel{}, ErrOutOfBounds
+	}
+	if err := ps.resolveColor(&pixel); err != nil {
+		return models.Pixel{}, err
+	}
+	if err := ps.bans.CheckPlacement(ctx, publicKey); err != nil {
+		return models.Pixel{}, err
+	}
+
+	teamID, err := ps.teamOf(ctx, publicKey)
+	if err != nil {
+		return models.Pixel{}, err
+	}
+
+	now := time.Now().UTC()
+	if err := ps.zones.CheckPlacement(pixel.X, pixel.Y, teamID, now); err != nil {
+		return models.Pixel{}, err
+	}
+	penalty, err := ps.territories.CheckPlacement(pixel.X, pixel.Y, teamID)
+	if err != nil {
+		return models.Pixel{}, err
+	}
+	keys := ps.cooldownKeys(publicKey, teamID, penalty)
+	if remaining := ps.cooldowns.reserve(now, keys...); remaining > 0 {
+		return models.Pixel{}, &CooldownError{Remaining: remaining}
+	}
+
+	pixel.PublicKey = publicKey
+	pixel.TeamID = teamID
+	pixel.PlacedAt = now
+
+	// Не сохранённая установка не должна стоить игроку паузы
+	previous, err := ps.repository.UpsertPixel(ctx, pixel)
+	if err != nil {
+		ps.cooldowns.release(now, keys...)
+		return models.Pixel{}, err
+	}
+
+	event := &models.PixelEvent{
+		X:         pixel.X,
+		Y:         pixel.Y,
+		Color:     pixel.Color,
+		PublicKey: pixel.PublicKey,
+		TeamID:    pixel.TeamID,
+		Bot:       pixel.Bot,
+		PlacedAt:  pixel.PlacedAt,
+	}
+	// Пиксель уже сохранён: установка состоялась и должна разойтись клиентам,
+	// поэтому сбои истории и счётчиков её не отменяют
+	if err := ps.repository.InsertPixelEvent(ctx, event); err != nil {
+		log.Printf("Failed to record history event for pixel (%d, %d) by %s: %v", pixel.X, pixel.Y, publicKey, err)
+	}
+	if err := ps.stats.RecordPlacement(ctx, pixel, previous); err != nil {
+		log.Printf("Failed to record placement stats for %s: %v", publicKey, err)
+	}
+	return pixel, nil
 }
 
-func (ps *pixelService) UpsertPixel(ctx context.Context, pixel models.Pixel) error {
-	return ps.repository.UpsertPixel(ctx, pixel)
+func (ps *pixelService) CooldownRemaining(ctx context.Context, publicKey string) (time.Duration, error) {
+	teamID, err := ps.teamOf(ctx, publicKey)
+	if err != nil {
+		return 0, err
+	}
+	return ps.cooldowns.remaining(time.Now().UTC(), ps.cooldownKeys(publicKey, teamID, 0)...), nil
+}
+
+func (ps *pixelService) Cooldown() time.Duration {
+	return ps.config.PlacementCooldown
+}
+
+func (ps *pixelService) Canvas() models.Canvas {
+	return models.Canvas{
+		Width:       ps.config.CanvasWidth,
+		Height:      ps.config.CanvasHeight,
+		Territories: ps.territories.Boundaries(),
+	}
+}
+
+func (ps *pixelService) Palette() *canvas.Palette {
+	return ps.palette
+}
+
+// resolveColor приводит цвет к каноническому виду палитры; индекс имеет приоритет над строкой
+func (ps *pixelService) resolveColor(pixel *models.Pixel) error {
+	if pixel.ColorIndex != nil {
+		hex, ok := ps.palette.Hex(*pixel.ColorIndex)
+		if !ok {
+			return ErrInvalidColor
+		}
+		pixel.Color = hex
+		return nil
+	}
+
+	index, ok := ps.palette.Index(pixel.Color)
+	if !ok {
+		return ErrInvalidColor
+	}
+	pixel.Color, _ = ps.palette.Hex(index)
+	pixel.ColorIndex = &index
+	return nil
+}
+
+// fillColorIndex проставляет индекс палитры для сохранённого пикселя, если цвет в палитре
+func (ps *pixelService) fillColorIndex(pixel *models.Pixel) {
+	if index, ok := ps.palette.Index(pixel.Color); ok {
+		pixel.ColorIndex = &index
+	}
+}
+
+// cooldownKeys - паузы кошелька и команды. penalty удлиняет паузу кошелька
+// перед установкой на чужую территорию
+func (ps *pixelService) cooldownKeys(publicKey, teamID string, penalty time.Duration) []cooldownKey {
+	keys := []cooldownKey{{key: "wallet:" + publicKey, period: ps.config.PlacementCooldown + penalty}}
+	team := cooldownKey{period: ps.config.TeamPlacementCooldown}
+	if teamID != "" {
+		team.key = "team:" + teamID
+	}
+	return append(keys, team)
+}
+
+// teamOf возвращает ID команды кошелька или пустую строку, если он не в команде
+func (ps *pixelService) teamOf(ctx context.Context, publicKey string) (string, error) {
+	teams, err := ps.teamRepository.GetTeamsByMember(ctx, publicKey)
+	if err != nil {
+		return "", err
+	}
+	if len(teams) == 0 {
+		return "", nil
+	}
+	return teams[0].ID.Hex(), nil
+}
+
+func (ps *pixelService) GetPixelHistory(ctx context.Context, query models.PixelHistoryQuery) (*models.PixelHistoryPage, error) {
+	if query.Width <= 0 || query.Height <= 0 || query.Offset < 0 || query.Limit < 0 {
+		return nil, ErrInvalidHistoryQuery
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return nil, ErrInvalidHistoryQuery
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultHistoryLimit
+	}
+	if query.Limit > maxHistoryLimit {
+		query.Limit = maxHistoryLimit
+	}
+
+	// Запрашиваем на одну запись больше, чтобы понять, есть ли следующая страница
+	limit := query.Limit
+	query.Limit++
+	events, err := ps.repository.GetPixelHistory(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.PixelHistoryPage{
+		Events: events,
+		Limit:  limit,
+		Offset: query.Offset,
+	}
+	if len(events) > limit {
+		page.Events = events[:limit]
+		page.HasMore = true
+	}
+	if page.Events == nil {
+		page.Events = []models.PixelEvent{}
+	}
+	return page, nil
 }
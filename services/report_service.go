@@ -0,0 +1,272 @@
+// services/report_service.go
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"your_project/canvas"
+	"your_project/models"
+	"your_project/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	maxReportSide       = 256 // Сторона области жалобы в клетках
+	maxReportReason     = 500
+	maxOpenReports      = 10 // Открытых жалоб на одного игрока
+	reportThumbnailSide = 256
+	defaultReportLimit  = 50
+	maxReportLimit      = 500
+)
+
+var (
+	ErrInvalidReport     = errors.New("report needs a reason and a region inside the canvas or a wallet")
+	ErrTooManyReports    = errors.New("too many open reports")
+	ErrReportNotFound    = errors.New("report not found")
+	ErrReportClosed      = errors.New("report is already resolved")
+	ErrInvalidAssignee   = errors.New("reports can only be assigned to moderators")
+	ErrInvalidResolution = errors.New("status must be actioned or dismissed")
+	ErrInvalidReportList = errors.New("invalid report list query")
+)
+
+// ReportRequest - жалоба игрока на область холста или кошелёк. У жалобы на
+// кошелёк область необязательна и показывает, где он нарисовал
+type ReportRequest struct {
+	Reporter  string
+	Type      models.ReportType
+	PublicKey string
+	Region    *models.Region
+	Reason    string
+}
+
+type ReportService interface {
+	CreateReport(ctx context.Context, req ReportRequest) (*models.Report, error)
+	GetReports(ctx context.Context, query models.ReportQuery) ([]models.Report, error)
+	// GetReport возвращает жалобу вместе с установками, на которые она ссылается
+	GetReport(ctx context.Context, id string) (*models.Report, []models.PixelEvent, error)
+	// GetThumbnail возвращает PNG области; у жалобы без области он пуст
+	GetThumbnail(ctx context.Context, id string) ([]byte, error)
+	// Assign и Resolve возвращают жалобу до и после изменения (для журнала аудита)
+	Assign(ctx context.Context, id, assignee string) (before, after *models.Report, err error)
+	Resolve(ctx context.Context, id string, status models.ReportStatus, resolvedBy, resolution string) (before, after *models.Report, err error)
+}
+
+type reportService struct {
+	repository      repositories.ReportRepository
+	pixelRepository repositories.PixelRepository
+	roles           RoleService
+	board           *canvas.Board
+	palette         *canvas.Palette
+}
+
+func NewReportService(repo repositories.ReportRepository, pixelRepo repositories.PixelRepository, roles RoleService, board *canvas.Board, palette *canvas.Palette) ReportService {
+	return &reportService{
+		repository:      repo,
+		pixelRepository: pixelRepo,
+		roles:           roles,
+		board:           board,
+		palette:         palette,
+	}
+}
+
+func (rs *reportService) CreateReport(ctx context.Context, req ReportRequest) (*models.Report, error) {
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || utf8.RuneCountInString(req.Reason) > maxReportReason {
+		return nil, ErrInvalidReport
+	}
+	switch req.Type {
+	case models.ReportRegion:
+		if req.Region == nil {
+			return nil, ErrInvalidReport
+		}
+		req.PublicKey = ""
+	case models.ReportWallet:
+		if !isValidPublicKey(req.PublicKey) || req.PublicKey == req.Reporter {
+			return nil, ErrInvalidReport
+		}
+	default:
+		return nil, ErrInvalidReport
+	}
+	if req.Region != nil && !rs.validRegion(*req.Region) {
+		return nil, ErrInvalidReport
+	}
+
+	open, err := rs.repository.CountOpenReports(ctx, req.Reporter)
+	if err != nil {
+		return nil, err
+	}
+	if open >= maxOpenReports {
+		return nil, ErrTooManyReports
+	}
+
+	now := time.Now().UTC()
+	report := &models.Report{
+		Reporter:  req.Reporter,
+		Type:      req.Type,
+		PublicKey: req.PublicKey,
+		Region:    req.Region,
+		Reason:    req.Reason,
+		Status:    models.ReportOpen,
+		CreatedAt: now,
+	}
+	if req.Region != nil {
+		if err := rs.captureRegion(ctx, report, now); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := rs.repository.CreateReport(ctx, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// captureRegion рендерит миниатюру области и запоминает установки, видимые в ней сейчас
+func (rs *reportService) captureRegion(ctx context.Context, report *models.Report, now time.Time) error {
+	region := report.Region
+	scale := max(1, reportThumbnailSide/max(region.Width, region.Height))
+	bounds := image.Rect(region.X, region.Y, region.X+region.Width, region.Y+region.Height)
+
+	report.CanvasVersion = rs.board.Version()
+	var thumbnail bytes.Buffer
+	if err := png.Encode(&thumbnail, canvas.Render(rs.board, rs.palette, bounds, scale)); err != nil {
+		return err
+	}
+	report.Thumbnail = thumbnail.Bytes()
+
+	states, err := rs.pixelRepository.GetCellStates(ctx, models.CellStateQuery{
+		X: region.X, Y: region.Y, Width: region.Width, Height: region.Height,
+		Before: now,
+	})
+	if err != nil {
+		return err
+	}
+	for _, event := range states {
+		// Очищенная откатом клетка пуста, ссылаться не на что
+		if event.Color != "" {
+			report.EventIDs = append(report.EventIDs, event.ID)
+		}
+	}
+	return nil
+}
+
+func (rs *reportService) validRegion(region models.Region) bool {
+	if region.Width <= 0 || region.Height <= 0 || region.Width > maxReportSide || region.Height > maxReportSide {
+		return false
+	}
+	bounds := image.Rect(0, 0, rs.board.Width(), rs.board.Height())
+	return image.Rect(region.X, region.Y, region.X+region.Width, region.Y+region.Height).In(bounds)
+}
+
+func (rs *reportService) GetReports(ctx context.Context, query models.ReportQuery) ([]models.Report, error) {
+	switch query.Status {
+	case "", models.ReportOpen, models.ReportActioned, models.ReportDismissed:
+	default:
+		return nil, ErrInvalidReportList
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultReportLimit
+	}
+	if query.Limit < 0 || query.Limit > maxReportLimit || query.Offset < 0 {
+		return nil, ErrInvalidReportList
+	}
+
+	reports, err := rs.repository.GetReports(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if reports == nil {
+		reports = []models.Report{}
+	}
+	return reports, nil
+}
+
+func (rs *reportService) GetReport(ctx context.Context, id string) (*models.Report, []models.PixelEvent, error) {
+	report, err := rs.findReport(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	events, err := rs.pixelRepository.GetEventsByIDs(ctx, report.EventIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	if events == nil {
+		events = []models.PixelEvent{}
+	}
+	return report, events, nil
+}
+
+func (rs *reportService) GetThumbnail(ctx context.Context, id string) ([]byte, error) {
+	report, err := rs.findReport(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return report.Thumbnail, nil
+}
+
+func (rs *reportService) Assign(ctx context.Context, id, assignee string) (*models.Report, *models.Report, error) {
+	role, err := rs.roles.RoleOf(ctx, assignee)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !role.AtLeast(models.RoleModerator) {
+		return nil, nil, ErrInvalidAssignee
+	}
+
+	report, err := rs.findReport(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	updated, err := rs.repository.AssignReport(ctx, report.ID, assignee, time.Now().UTC())
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil, ErrReportClosed
+		}
+		return nil, nil, err
+	}
+	return report, updated, nil
+}
+
+func (rs *reportService) Resolve(ctx context.Context, id string, status models.ReportStatus, resolvedBy, resolution string) (*models.Report, *models.Report, error) {
+	if status != models.ReportActioned && status != models.ReportDismissed {
+		return nil, nil, ErrInvalidResolution
+	}
+
+	report, err := rs.findReport(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	updated, err := rs.repository.ResolveReport(ctx, report.ID, status, resolvedBy, strings.TrimSpace(resolution), time.Now().UTC())
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil, ErrReportClosed
+		}
+		return nil, nil, err
+	}
+	return report, updated, nil
+}
+
+// findReport читает жалобу по ID из запроса
+func (rs *reportService) findReport(ctx context.Context, id string) (*models.Report, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrReportNotFound
+	}
+	report, err := rs.repository.GetReport(ctx, objectID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrReportNotFound
+		}
+		return nil, err
+	}
+	return report, nil
+}
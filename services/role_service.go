@@ -0,0 +1,102 @@
+// services/role_service.go
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"your_project/models"
+	"your_project/repositories"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrInvalidRole         = errors.New("invalid role")
+	ErrCannotChangeOwnRole = errors.New("cannot change your own role")
+)
+
+type RoleService interface {
+	// RoleOf возвращает роль кошелька; без записи - игрок
+	RoleOf(ctx context.Context, publicKey string) (models.Role, error)
+	SetRole(ctx context.Context, actor, publicKey string, role models.Role) (*models.UserRole, error)
+	GetPrivileged(ctx context.Context) ([]models.UserRole, error)
+	// BootstrapAdmins назначает админами кошельки из конфигурации (ADMIN_PUBLIC_KEYS),
+	// у которых ещё нет сохранённой роли
+	BootstrapAdmins(ctx context.Context, publicKeys []string) error
+}
+
+type roleService struct {
+	repository repositories.RoleRepository
+}
+
+func NewRoleService(repo repositories.RoleRepository) RoleService {
+	return &roleService{
+		repository: repo,
+	}
+}
+
+func (rs *roleService) RoleOf(ctx context.Context, publicKey string) (models.Role, error) {
+	role, err := rs.repository.GetRole(ctx, publicKey)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.RolePlayer, nil
+		}
+		return "", err
+	}
+	return role.Role, nil
+}
+
+func (rs *roleService) SetRole(ctx context.Context, actor, publicKey string, role models.Role) (*models.UserRole, error) {
+	if !role.Valid() {
+		return nil, ErrInvalidRole
+	}
+	if !isValidPublicKey(publicKey) {
+		return nil, ErrInvalidPublicKey
+	}
+	// Иначе последний админ может случайно лишить себя прав
+	if actor == publicKey {
+		return nil, ErrCannotChangeOwnRole
+	}
+
+	userRole := &models.UserRole{
+		PublicKey: publicKey,
+		Role:      role,
+		UpdatedAt: time.Now().UTC(),
+		UpdatedBy: actor,
+	}
+	if err := rs.repository.SetRole(ctx, userRole); err != nil {
+		return nil, err
+	}
+	return userRole, nil
+}
+
+func (rs *roleService) GetPrivileged(ctx context.Context) ([]models.UserRole, error) {
+	roles, err := rs.repository.GetPrivileged(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if roles == nil {
+		roles = []models.UserRole{}
+	}
+	return roles, nil
+}
+
+func (rs *roleService) BootstrapAdmins(ctx context.Context, publicKeys []string) error {
+	for _, publicKey := range publicKeys {
+		if !isValidPublicKey(publicKey) {
+			return ErrInvalidPublicKey
+		}
+		// Роль, уже заданную через API (в том числе снятие админа), не перезаписываем
+		err := rs.repository.InsertRole(ctx, &models.UserRole{
+			PublicKey: publicKey,
+			Role:      models.RoleAdmin,
+			UpdatedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
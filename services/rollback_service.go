@@ -0,0 +1,236 @@
+// services/rollback_service.go
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"your_project/canvas"
+	"your_project/config"
+	"your_project/models"
+	"your_project/repositories"
+)
+
+// maxRollbackCells - сколько клеток можно откатить за раз (площадь региона
+// или клетки одного кошелька)
+const maxRollbackCells = 50000
+
+var (
+	ErrInvalidRollback  = errors.New("invalid rollback request")
+	ErrRollbackTooLarge = errors.New("too many cells to roll back at once")
+)
+
+// RestoreRegionRequest - вернуть прямоугольник к состоянию на момент At
+type RestoreRegionRequest struct {
+	X      int
+	Y      int
+	Width  int
+	Height int
+	At     time.Time
+	Actor  string
+	DryRun bool
+}
+
+// RevertWalletRequest - отменить установки кошелька за [From, To)
+type RevertWalletRequest struct {
+	PublicKey string
+	From      time.Time
+	To        time.Time
+	Actor     string
+	DryRun    bool
+}
+
+// RollbackService восстанавливает холст по истории установок. Изменённые
+// пиксели вызывающий рассылает через хаб
+type RollbackService interface {
+	RestoreRegion(ctx context.Context, req RestoreRegionRequest) (*models.RollbackResult, error)
+	RevertWallet(ctx context.Context, req RevertWalletRequest) (*models.RollbackResult, error)
+}
+
+type rollbackService struct {
+	repository repositories.PixelRepository
+	stats      StatsService
+	config     *config.Config
+	palette    *canvas.Palette
+}
+
+func NewRollbackService(repo repositories.PixelRepository, stats StatsService, cfg *config.Config, palette *canvas.Palette) RollbackService {
+	return &rollbackService{
+		repository: repo,
+		stats:      stats,
+		config:     cfg,
+		palette:    palette,
+	}
+}
+
+func (rs *rollbackService) RestoreRegion(ctx context.Context, req RestoreRegionRequest) (*models.RollbackResult, error) {
+	if req.Width <= 0 || req.Height <= 0 || req.At.IsZero() || req.At.After(time.Now()) ||
+		!rs.contains(req.X, req.Y) || !rs.contains(req.X+req.Width-1, req.Y+req.Height-1) {
+		return nil, ErrInvalidRollback
+	}
+	if req.Width*req.Height > maxRollbackCells {
+		return nil, ErrRollbackTooLarge
+	}
+
+	states, err := rs.repository.GetCellStates(ctx, models.CellStateQuery{
+		X: req.X, Y: req.Y, Width: req.Width, Height: req.Height,
+		Before: req.At,
+	})
+	if err != nil {
+		return nil, err
+	}
+	current, err := rs.repository.GetPixelsInRegion(ctx, req.X, req.Y, req.Width, req.Height)
+	if err != nil {
+		return nil, err
+	}
+
+	// Затрагиваются все клетки, у которых есть прошлое или текущее состояние
+	cells := make(map[models.Cell]bool, len(states)+len(current))
+	for _, event := range states {
+		cells[models.Cell{X: event.X, Y: event.Y}] = true
+	}
+	for _, pixel := range current {
+		cells[models.Cell{X: pixel.X, Y: pixel.Y}] = true
+	}
+	return rs.apply(ctx, cells, states, current, req.Actor, req.DryRun)
+}
+
+func (rs *rollbackService) RevertWallet(ctx context.Context, req RevertWalletRequest) (*models.RollbackResult, error) {
+	if req.PublicKey == "" || req.From.IsZero() || req.To.IsZero() || !req.From.Before(req.To) {
+		return nil, ErrInvalidRollback
+	}
+
+	touched, err := rs.repository.GetWalletCells(ctx, req.PublicKey, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+	if len(touched) > maxRollbackCells {
+		return nil, ErrRollbackTooLarge
+	}
+	if len(touched) == 0 {
+		return &models.RollbackResult{Changed: []models.Pixel{}, Replaced: []models.Pixel{}, DryRun: req.DryRun}, nil
+	}
+
+	// Историю и текущее состояние читаем по охватывающему прямоугольнику,
+	// лишние клетки отсекает cells
+	cells := make(map[models.Cell]bool, len(touched))
+	minX, minY, maxX, maxY := touched[0].X, touched[0].Y, touched[0].X, touched[0].Y
+	for _, cell := range touched {
+		cells[cell] = true
+		minX, minY = min(minX, cell.X), min(minY, cell.Y)
+		maxX, maxY = max(maxX, cell.X), max(maxY, cell.Y)
+	}
+	width, height := maxX-minX+1, maxY-minY+1
+
+	states, err := rs.repository.GetCellStates(ctx, models.CellStateQuery{
+		X: minX, Y: minY, Width: width, Height: height,
+		ExcludePublicKey: req.PublicKey,
+		ExcludeFrom:      req.From,
+		ExcludeTo:        req.To,
+	})
+	if err != nil {
+		return nil, err
+	}
+	current, err := rs.repository.GetPixelsInRegion(ctx, minX, minY, width, height)
+	if err != nil {
+		return nil, err
+	}
+	return rs.apply(ctx, cells, states, current, req.Actor, req.DryRun)
+}
+
+// apply сравнивает желаемое состояние клеток с текущим и записывает разницу:
+// пиксели восстанавливаются с исходным автором и временем, а в историю
+// добавляется событие отката. Клетка без желаемого состояния очищается
+func (rs *rollbackService) apply(ctx context.Context, cells map[models.Cell]bool, states []models.PixelEvent, current []models.Pixel, actor string, dryRun bool) (*models.RollbackResult, error) {
+	desired := make(map[models.Cell]models.PixelEvent, len(states))
+	for _, event := range states {
+		// Очистка, записанная прошлым откатом, - это тоже пустая клетка
+		if event.Color != "" {
+			desired[models.Cell{X: event.X, Y: event.Y}] = event
+		}
+	}
+	existing := make(map[models.Cell]models.Pixel, len(current))
+	for _, pixel := range current {
+		existing[models.Cell{X: pixel.X, Y: pixel.Y}] = pixel
+	}
+
+	now := time.Now().UTC()
+	changed := []models.Pixel{}
+	replaced := []models.Pixel{}
+	var events []models.PixelEvent
+	for cell := range cells {
+		target, restore := desired[cell]
+		pixel, exists := existing[cell]
+		if !restore && !exists {
+			continue
+		}
+		if restore && exists && pixel.Color == target.Color && pixel.PublicKey == target.PublicKey {
+			continue
+		}
+
+		restored := models.Pixel{X: cell.X, Y: cell.Y}
+		if restore {
+			restored.Color = target.Color
+			restored.PublicKey = target.PublicKey
+			restored.TeamID = target.TeamID
+			restored.Bot = target.Bot
+			restored.PlacedAt = target.PlacedAt
+		}
+		changed = append(changed, restored)
+		replaced = append(replaced, pixel)
+		events = append(events, models.PixelEvent{
+			X:          restored.X,
+			Y:          restored.Y,
+			Color:      restored.Color,
+			PublicKey:  restored.PublicKey,
+			TeamID:     restored.TeamID,
+			Bot:        restored.Bot,
+			RollbackBy: actor,
+			PlacedAt:   now,
+		})
+	}
+
+	result := &models.RollbackResult{Changed: changed, Replaced: replaced, Count: len(changed), DryRun: dryRun}
+	if dryRun {
+		return result, nil
+	}
+
+	if err := rs.repository.RestorePixels(ctx, changed); err != nil {
+		return nil, err
+	}
+	if err := rs.repository.InsertPixelEvents(ctx, events); err != nil {
+		return nil, err
+	}
+	if err := rs.stats.RecordRestore(ctx, replaced, changed); err != nil {
+		log.Printf("Failed to update owned pixel stats after rollback by %s: %v", actor, err)
+	}
+
+	for i := range changed {
+		rs.fillColorIndex(&changed[i])
+		rs.fillColorIndex(&replaced[i])
+	}
+	return result, nil
+}
+
+// fillColorIndex проставляет индекс палитры; цвет не из палитры (до миграции)
+// показывается ближайшим, очищенная клетка остаётся без индекса
+func (rs *rollbackService) fillColorIndex(pixel *models.Pixel) {
+	if pixel.Color == "" {
+		return
+	}
+	index, ok := rs.palette.Index(pixel.Color)
+	if !ok {
+		c, err := canvas.ParseHexColor(pixel.Color)
+		if err != nil {
+			return
+		}
+		index = rs.palette.Nearest(c)
+	}
+	pixel.ColorIndex = &index
+}
+
+func (rs *rollbackService) contains(x, y int) bool {
+	return models.Canvas{Width: rs.config.CanvasWidth, Height: rs.config.CanvasHeight}.Contains(x, y)
+}
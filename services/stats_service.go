@@ -0,0 +1,228 @@
+// services/stats_service.go
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"your_project/models"
+	"your_project/repositories"
+)
+
+const (
+	// statsBucket - шаг корзин установок; границы окон "час" и "сутки" округлены до него
+	statsBucket             = 5 * time.Minute
+	defaultLeaderboardLimit = 20
+	maxLeaderboardLimit     = 100
+)
+
+var ErrInvalidLeaderboard = errors.New("sort must be total, owned, hour or day and limit between 1 and 100")
+
+// StatsService ведёт счётчики установок кошельков и команд. Счётчики
+// обновляются на каждую установку, а не пересчитываются по истории
+type StatsService interface {
+	// Backfill пересчитывает счётчики по истории, если полный пересчёт ещё
+	// ни разу не завершался (первый запуск или пересчёт прервался)
+	Backfill(ctx context.Context) error
+	// Rebuild пересчитывает счётчики заново, исправляя расхождения после
+	// сбоев записи (см. cmd/rebuild_stats)
+	Rebuild(ctx context.Context) error
+	// RecordPlacement учитывает установку pixel поверх previous (nil - клетка была пуста)
+	RecordPlacement(ctx context.Context, pixel models.Pixel, previous *models.Pixel) error
+	// RecordRestore переносит занятые клетки после отката: before[i] заменён на after[i].
+	// Пиксель без PublicKey - пустая клетка
+	RecordRestore(ctx context.Context, before, after []models.Pixel) error
+	GetTeamLeaderboard(ctx context.Context, query models.LeaderboardQuery) ([]models.TeamStats, error)
+	GetPlayerLeaderboard(ctx context.Context, query models.LeaderboardQuery) ([]models.PlayerStats, error)
+}
+
+type statsService struct {
+	repository     repositories.StatsRepository
+	teamRepository repositories.TeamRepository
+}
+
+func NewStatsService(repo repositories.StatsRepository, teamRepo repositories.TeamRepository) StatsService {
+	return &statsService{
+		repository:     repo,
+		teamRepository: teamRepo,
+	}
+}
+
+func (ss *statsService) Backfill(ctx context.Context) error {
+	built, err := ss.repository.IsBuilt(ctx)
+	if err != nil || built {
+		return err
+	}
+	return ss.Rebuild(ctx)
+}
+
+func (ss *statsService) Rebuild(ctx context.Context) error {
+	return ss.repository.Rebuild(ctx, statsBucket, windowStart(time.Now().UTC(), 24*time.Hour))
+}
+
+func (ss *statsService) RecordPlacement(ctx context.Context, pixel models.Pixel, previous *models.Pixel) error {
+	err := ss.repository.RecordPlacement(ctx, pixel.PublicKey, pixel.TeamID, pixel.PlacedAt, pixel.PlacedAt.Truncate(statsBucket))
+	if err != nil {
+		return err
+	}
+	before := models.Pixel{}
+	if previous != nil {
+		before = *previous
+	}
+	return ss.RecordRestore(ctx, []models.Pixel{before}, []models.Pixel{pixel})
+}
+
+func (ss *statsService) RecordRestore(ctx context.Context, before, after []models.Pixel) error {
+	wallets := make(map[string]int64)
+	teams := make(map[string]int64)
+	for i := range before {
+		wallets[before[i].PublicKey]--
+		wallets[after[i].PublicKey]++
+		teams[before[i].TeamID]--
+		teams[after[i].TeamID]++
+	}
+	// Пустые ключи и нулевые разницы (клетку перекрасил её же владелец) репозиторий пропускает
+	if err := ss.repository.AdjustOwned(ctx, models.StatsWallet, wallets); err != nil {
+		return err
+	}
+	return ss.repository.AdjustOwned(ctx, models.StatsTeam, teams)
+}
+
+func (ss *statsService) GetTeamLeaderboard(ctx context.Context, query models.LeaderboardQuery) ([]models.TeamStats, error) {
+	stats, err := ss.leaderboard(ctx, models.StatsTeam, query)
+	if err != nil {
+		return nil, err
+	}
+	teams, err := ss.teamRepository.GetTeams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(teams))
+	for _, team := range teams {
+		names[team.ID.Hex()] = team.Name
+	}
+
+	leaderboard := make([]models.TeamStats, len(stats))
+	for i, s := range stats {
+		s.TeamID = s.Key
+		leaderboard[i] = models.TeamStats{Rank: i + 1, TeamName: names[s.Key], PlacementStats: s}
+	}
+	return leaderboard, nil
+}
+
+func (ss *statsService) GetPlayerLeaderboard(ctx context.Context, query models.LeaderboardQuery) ([]models.PlayerStats, error) {
+	stats, err := ss.leaderboard(ctx, models.StatsWallet, query)
+	if err != nil {
+		return nil, err
+	}
+	leaderboard := make([]models.PlayerStats, len(stats))
+	for i, s := range stats {
+		leaderboard[i] = models.PlayerStats{Rank: i + 1, PublicKey: s.Key, PlacementStats: s}
+	}
+	return leaderboard, nil
+}
+
+// leaderboard выбирает первые query.Limit счётчиков по нужному полю и
+// дополняет их числом установок за час и сутки
+func (ss *statsService) leaderboard(ctx context.Context, scope models.StatsScope, query models.LeaderboardQuery) ([]models.PlacementStats, error) {
+	if query.Sort == "" {
+		query.Sort = models.SortByTotal
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultLeaderboardLimit
+	}
+	if query.Limit < 0 || query.Limit > maxLeaderboardLimit {
+		return nil, ErrInvalidLeaderboard
+	}
+
+	now := time.Now().UTC()
+	lastHour, lastDay := windowStart(now, time.Hour), windowStart(now, 24*time.Hour)
+
+	var stats []models.PlacementStats
+	switch query.Sort {
+	case models.SortByTotal, models.SortByOwned:
+		var err error
+		if stats, err = ss.repository.GetStats(ctx, scope, string(query.Sort), query.Limit); err != nil {
+			return nil, err
+		}
+	case models.SortByLastHour, models.SortByLastDay:
+		since := lastHour
+		if query.Sort == models.SortByLastDay {
+			since = lastDay
+		}
+		top, err := ss.repository.GetWindowCounts(ctx, scope, nil, since, query.Limit)
+		if err != nil {
+			return nil, err
+		}
+		if stats, err = ss.statsInOrder(ctx, scope, top); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrInvalidLeaderboard
+	}
+
+	keys := make([]string, len(stats))
+	for i := range stats {
+		keys[i] = stats[i].Key
+	}
+	hourCounts, err := ss.windowCounts(ctx, scope, keys, lastHour)
+	if err != nil {
+		return nil, err
+	}
+	dayCounts, err := ss.windowCounts(ctx, scope, keys, lastDay)
+	if err != nil {
+		return nil, err
+	}
+	for i := range stats {
+		stats[i].LastHour = hourCounts[stats[i].Key]
+		stats[i].LastDay = dayCounts[stats[i].Key]
+	}
+	return stats, nil
+}
+
+// statsInOrder читает счётчики ключей в порядке окна top
+func (ss *statsService) statsInOrder(ctx context.Context, scope models.StatsScope, top []models.WindowCount) ([]models.PlacementStats, error) {
+	keys := make([]string, len(top))
+	for i := range top {
+		keys[i] = top[i].Key
+	}
+	found, err := ss.repository.GetStatsByKeys(ctx, scope, keys)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]models.PlacementStats, len(found))
+	for _, s := range found {
+		byKey[s.Key] = s
+	}
+
+	stats := make([]models.PlacementStats, len(top))
+	for i, count := range top {
+		s, ok := byKey[count.Key]
+		if !ok {
+			s = models.PlacementStats{Scope: scope, Key: count.Key}
+		}
+		stats[i] = s
+	}
+	return stats, nil
+}
+
+func (ss *statsService) windowCounts(ctx context.Context, scope models.StatsScope, keys []string, since time.Time) (map[string]int64, error) {
+	counts := make(map[string]int64, len(keys))
+	if len(keys) == 0 {
+		return counts, nil
+	}
+	window, err := ss.repository.GetWindowCounts(ctx, scope, keys, since, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, count := range window {
+		counts[count.Key] = count.Count
+	}
+	return counts, nil
+}
+
+// windowStart - начало первой корзины, попадающей в окно длиной window до now
+func windowStart(now time.Time, window time.Duration) time.Time {
+	return now.Add(-window).Truncate(statsBucket)
+}
@@ -19,15 +19,21 @@ type TeamService interface {
 
 type teamService struct {
 	repository repositories.TeamRepository
+	bans       BanService
 }
 
-func NewTeamService(repo repositories.TeamRepository) TeamService {
+func NewTeamService(repo repositories.TeamRepository, bans BanService) TeamService {
 	return &teamService{
 		repository: repo,
+		bans:       bans,
 	}
 }
 
+// CreateTeam и JoinTeam возвращают *BanError для кошельков с баном
 func (ts *teamService) CreateTeam(ctx context.Context, name string, creator string) (*models.Team, error) {
+	if err := ts.bans.CheckPlacement(ctx, creator); err != nil {
+		return nil, err
+	}
 	team := &models.Team{
 		Name:    name,
 		Members: []string{creator},
@@ -60,6 +66,9 @@ func (ts *teamService) GetTeamMembers(ctx context.Context, teamID string) ([]str
 }
 
 func (ts *teamService) JoinTeam(ctx context.Context, teamID string, member string) error {
+	if err := ts.bans.CheckPlacement(ctx, member); err != nil {
+		return err
+	}
 	return ts.repository.AddMember(ctx, teamID, member)
 }
 
@@ -0,0 +1,296 @@
+// services/territory_service.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"your_project/config"
+	"your_project/models"
+	"your_project/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrInvalidTerritory    = errors.New("territory needs a region inside the canvas within the area limit and a restricted or penalty policy")
+	ErrTerritoryNotFound   = errors.New("territory not found")
+	ErrTerritoryOverlap    = errors.New("territory overlaps another territory or a protected zone")
+	ErrTooManyTerritories  = errors.New("team has too many territory claims")
+	ErrTerritoryNotPending = errors.New("territory is not in a state that allows this change")
+	ErrNotInTeam           = errors.New("wallet is not in a team")
+)
+
+// TerritoryError - клетка на территории чужой команды с policy "restricted"
+type TerritoryError struct {
+	Territory *models.Territory
+}
+
+func (e *TerritoryError) Error() string {
+	return fmt.Sprintf("cell belongs to the territory of team %q", e.Territory.TeamName)
+}
+
+type ClaimRequest struct {
+	PublicKey string
+	Region    models.Region
+	Policy    models.TerritoryPolicy
+}
+
+// TerritoryService ведёт заявки команд на территории. Действующие территории
+// держатся в памяти: они проверяются на каждую установку и уходят клиентам
+// в метаданных холста
+type TerritoryService interface {
+	// Load читает действующие территории из базы; вызывается при старте
+	Load(ctx context.Context) error
+	// Claim подаёт заявку от команды кошелька; территория начнёт действовать после одобрения
+	Claim(ctx context.Context, req ClaimRequest) (*models.Territory, error)
+	// Release снимает заявку или действующую территорию своей команды
+	Release(ctx context.Context, id, publicKey string) (*models.Territory, error)
+	GetTerritories(ctx context.Context, query models.TerritoryQuery) ([]models.Territory, error)
+	// Approve и Reject возвращают заявку до и после решения (для журнала аудита).
+	// Reject отзывает и уже действующую территорию
+	Approve(ctx context.Context, id, reviewer, note string) (before, after *models.Territory, err error)
+	Reject(ctx context.Context, id, reviewer, note string) (before, after *models.Territory, err error)
+	// Boundaries - действующие территории для метаданных холста
+	Boundaries() []models.TerritoryBoundary
+	// CheckPlacement возвращает *TerritoryError, если команде нельзя ставить в клетку,
+	// или прибавку к паузе для установки на чужой территории
+	CheckPlacement(x, y int, teamID string) (time.Duration, error)
+}
+
+type territoryService struct {
+	repository     repositories.TerritoryRepository
+	teamRepository repositories.TeamRepository
+	bans           BanService
+	zones          ZoneService
+	config         *config.Config
+	mutex          sync.RWMutex
+	approved       []models.Territory
+	boundaries     []models.TerritoryBoundary
+}
+
+func NewTerritoryService(repo repositories.TerritoryRepository, teamRepo repositories.TeamRepository, bans BanService, zones ZoneService, cfg *config.Config) TerritoryService {
+	return &territoryService{
+		repository:     repo,
+		teamRepository: teamRepo,
+		bans:           bans,
+		zones:          zones,
+		config:         cfg,
+		boundaries:     []models.TerritoryBoundary{},
+	}
+}
+
+func (ts *territoryService) Load(ctx context.Context) error {
+	approved, err := ts.repository.GetTerritories(ctx, models.TerritoryQuery{Status: models.TerritoryApproved})
+	if err != nil {
+		return err
+	}
+	// Срезы только заменяются целиком, поэтому Boundaries отдаёт их без копирования
+	boundaries := make([]models.TerritoryBoundary, len(approved))
+	for i := range approved {
+		boundaries[i] = approved[i].Boundary()
+	}
+
+	ts.mutex.Lock()
+	ts.approved = approved
+	ts.boundaries = boundaries
+	ts.mutex.Unlock()
+	return nil
+}
+
+func (ts *territoryService) Boundaries() []models.TerritoryBoundary {
+	ts.mutex.RLock()
+	defer ts.mutex.RUnlock()
+	return ts.boundaries
+}
+
+func (ts *territoryService) CheckPlacement(x, y int, teamID string) (time.Duration, error) {
+	ts.mutex.RLock()
+	defer ts.mutex.RUnlock()
+
+	for i := range ts.approved {
+		territory := &ts.approved[i]
+		if !territory.Contains(x, y) || territory.TeamID == teamID {
+			continue
+		}
+		if territory.Policy == models.TerritoryRestricted {
+			owned := *territory
+			return 0, &TerritoryError{Territory: &owned}
+		}
+		return ts.config.TerritoryPenalty, nil
+	}
+	return 0, nil
+}
+
+func (ts *territoryService) Claim(ctx context.Context, req ClaimRequest) (*models.Territory, error) {
+	r := req.Region
+	if r.Width <= 0 || r.Height <= 0 || r.X < 0 || r.Y < 0 ||
+		r.X+r.Width > ts.config.CanvasWidth || r.Y+r.Height > ts.config.CanvasHeight ||
+		r.Width*r.Height > ts.config.TerritoryMaxArea {
+		return nil, ErrInvalidTerritory
+	}
+	if req.Policy != models.TerritoryRestricted && req.Policy != models.TerritoryPenalty {
+		return nil, ErrInvalidTerritory
+	}
+	if err := ts.bans.CheckPlacement(ctx, req.PublicKey); err != nil {
+		return nil, err
+	}
+
+	team, err := ts.teamOf(ctx, req.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	claimed, err := ts.repository.GetClaimed(ctx)
+	if err != nil {
+		return nil, err
+	}
+	count := 0
+	for _, territory := range claimed {
+		if territory.Region.Overlaps(r) {
+			return nil, ErrTerritoryOverlap
+		}
+		if territory.TeamID == team.ID.Hex() {
+			count++
+		}
+	}
+	if count >= ts.config.TerritoryMaxPerTeam {
+		return nil, ErrTooManyTerritories
+	}
+	for _, zone := range ts.zones.GetZones(false) {
+		if zone.Bounds().Overlaps(r) {
+			return nil, ErrTerritoryOverlap
+		}
+	}
+
+	territory := &models.Territory{
+		TeamID:      team.ID.Hex(),
+		TeamName:    team.Name,
+		Region:      r,
+		Policy:      req.Policy,
+		Status:      models.TerritoryPending,
+		RequestedBy: req.PublicKey,
+		RequestedAt: time.Now().UTC(),
+	}
+	if err := ts.repository.CreateTerritory(ctx, territory); err != nil {
+		return nil, err
+	}
+	return territory, nil
+}
+
+func (ts *territoryService) Release(ctx context.Context, id, publicKey string) (*models.Territory, error) {
+	territory, err := ts.findTerritory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	team, err := ts.teamOf(ctx, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	// Чужую заявку не видно, как и несуществующую
+	if territory.TeamID != team.ID.Hex() {
+		return nil, ErrTerritoryNotFound
+	}
+
+	return ts.setStatus(ctx, territory.ID,
+		[]models.TerritoryStatus{models.TerritoryPending, models.TerritoryApproved},
+		models.TerritoryReleased, publicKey, "")
+}
+
+func (ts *territoryService) GetTerritories(ctx context.Context, query models.TerritoryQuery) ([]models.Territory, error) {
+	switch query.Status {
+	case "", models.TerritoryPending, models.TerritoryApproved, models.TerritoryRejected, models.TerritoryReleased:
+	default:
+		return nil, ErrInvalidTerritory
+	}
+	territories, err := ts.repository.GetTerritories(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if territories == nil {
+		territories = []models.Territory{}
+	}
+	return territories, nil
+}
+
+func (ts *territoryService) Approve(ctx context.Context, id, reviewer, note string) (*models.Territory, *models.Territory, error) {
+	territory, err := ts.findTerritory(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Пока заявка ждала, на этом месте могла появиться защищённая зона
+	for _, zone := range ts.zones.GetZones(false) {
+		if zone.Bounds().Overlaps(territory.Region) {
+			return nil, nil, ErrTerritoryOverlap
+		}
+	}
+
+	approved, err := ts.setStatus(ctx, territory.ID,
+		[]models.TerritoryStatus{models.TerritoryPending},
+		models.TerritoryApproved, reviewer, note)
+	if err != nil {
+		return nil, nil, err
+	}
+	return territory, approved, nil
+}
+
+func (ts *territoryService) Reject(ctx context.Context, id, reviewer, note string) (*models.Territory, *models.Territory, error) {
+	territory, err := ts.findTerritory(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	rejected, err := ts.setStatus(ctx, territory.ID,
+		[]models.TerritoryStatus{models.TerritoryPending, models.TerritoryApproved},
+		models.TerritoryRejected, reviewer, note)
+	if err != nil {
+		return nil, nil, err
+	}
+	return territory, rejected, nil
+}
+
+// setStatus меняет статус заявки и перечитывает действующие территории
+func (ts *territoryService) setStatus(ctx context.Context, id primitive.ObjectID, from []models.TerritoryStatus, status models.TerritoryStatus, by, note string) (*models.Territory, error) {
+	territory, err := ts.repository.SetStatus(ctx, id, from, status, by, strings.TrimSpace(note), time.Now().UTC())
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrTerritoryNotPending
+		}
+		return nil, err
+	}
+	if err := ts.Load(ctx); err != nil {
+		return nil, err
+	}
+	return territory, nil
+}
+
+func (ts *territoryService) findTerritory(ctx context.Context, id string) (*models.Territory, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrTerritoryNotFound
+	}
+	territory, err := ts.repository.GetTerritory(ctx, objectID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrTerritoryNotFound
+		}
+		return nil, err
+	}
+	return territory, nil
+}
+
+// teamOf возвращает команду кошелька или ErrNotInTeam
+func (ts *territoryService) teamOf(ctx context.Context, publicKey string) (*models.Team, error) {
+	teams, err := ts.teamRepository.GetTeamsByMember(ctx, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(teams) == 0 {
+		return nil, ErrNotInTeam
+	}
+	return &teams[0], nil
+}
@@ -0,0 +1,246 @@
+// services/zone_service.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"your_project/config"
+	"your_project/models"
+	"your_project/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	maxZones           = 100
+	maxZoneName        = 64
+	maxPolygonVertices = 64
+)
+
+var (
+	ErrInvalidZone  = errors.New("zone needs a name and a rectangle or polygon inside the canvas")
+	ErrZoneNotFound = errors.New("zone not found")
+	ErrTooManyZones = errors.New("too many protected zones")
+)
+
+// ProtectedError - клетка в действующей защищённой зоне, а команда не в списке разрешённых
+type ProtectedError struct {
+	Zone *models.Zone
+}
+
+func (e *ProtectedError) Error() string {
+	return fmt.Sprintf("cell is inside protected zone %q", e.Zone.Name)
+}
+
+type ZoneRequest struct {
+	Name         string
+	Shape        models.ZoneShape
+	Region       *models.Region
+	Polygon      []models.Cell
+	StartsAt     *time.Time
+	EndsAt       *time.Time
+	AllowedTeams []string
+	Actor        string
+}
+
+// ZoneService хранит защищённые зоны в памяти: проверка на каждую установку
+// не ходит в базу. Кэш перечитывается после каждого изменения
+type ZoneService interface {
+	// Load читает зоны из базы; вызывается при старте
+	Load(ctx context.Context) error
+	// GetZones возвращает зоны; без includeEnded - только действующие и будущие
+	GetZones(includeEnded bool) []models.Zone
+	CreateZone(ctx context.Context, req ZoneRequest) (*models.Zone, error)
+	UpdateZone(ctx context.Context, id string, req ZoneRequest) (before, after *models.Zone, err error)
+	DeleteZone(ctx context.Context, id string) (*models.Zone, error)
+	// CheckPlacement возвращает *ProtectedError, если команде нельзя ставить в клетку
+	CheckPlacement(x, y int, teamID string, now time.Time) error
+}
+
+type zoneService struct {
+	repository repositories.ZoneRepository
+	config     *config.Config
+	mutex      sync.RWMutex
+	zones      []models.Zone
+}
+
+func NewZoneService(repo repositories.ZoneRepository, cfg *config.Config) ZoneService {
+	return &zoneService{
+		repository: repo,
+		config:     cfg,
+	}
+}
+
+func (zs *zoneService) Load(ctx context.Context) error {
+	zones, err := zs.repository.GetZones(ctx)
+	if err != nil {
+		return err
+	}
+	zs.mutex.Lock()
+	zs.zones = zones
+	zs.mutex.Unlock()
+	return nil
+}
+
+func (zs *zoneService) GetZones(includeEnded bool) []models.Zone {
+	now := time.Now()
+	zs.mutex.RLock()
+	defer zs.mutex.RUnlock()
+
+	zones := make([]models.Zone, 0, len(zs.zones))
+	for _, zone := range zs.zones {
+		if includeEnded || zone.EndsAt == nil || now.Before(*zone.EndsAt) {
+			zones = append(zones, zone)
+		}
+	}
+	return zones
+}
+
+func (zs *zoneService) CheckPlacement(x, y int, teamID string, now time.Time) error {
+	zs.mutex.RLock()
+	defer zs.mutex.RUnlock()
+
+	for i := range zs.zones {
+		zone := &zs.zones[i]
+		if zone.ActiveAt(now) && zone.Contains(x, y) && !zone.Allows(teamID) {
+			protected := *zone
+			return &ProtectedError{Zone: &protected}
+		}
+	}
+	return nil
+}
+
+func (zs *zoneService) CreateZone(ctx context.Context, req ZoneRequest) (*models.Zone, error) {
+	zone, err := zs.buildZone(req)
+	if err != nil {
+		return nil, err
+	}
+	zs.mutex.RLock()
+	count := len(zs.zones)
+	zs.mutex.RUnlock()
+	if count >= maxZones {
+		return nil, ErrTooManyZones
+	}
+
+	zone.CreatedBy = req.Actor
+	zone.CreatedAt = zone.UpdatedAt
+	if err := zs.repository.CreateZone(ctx, zone); err != nil {
+		return nil, err
+	}
+	return zone, zs.Load(ctx)
+}
+
+func (zs *zoneService) UpdateZone(ctx context.Context, id string, req ZoneRequest) (*models.Zone, *models.Zone, error) {
+	before, err := zs.findZone(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	zone, err := zs.buildZone(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	zone.ID = before.ID
+	zone.CreatedBy = before.CreatedBy
+	zone.CreatedAt = before.CreatedAt
+	if err := zs.repository.ReplaceZone(ctx, zone); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil, ErrZoneNotFound
+		}
+		return nil, nil, err
+	}
+	return before, zone, zs.Load(ctx)
+}
+
+func (zs *zoneService) DeleteZone(ctx context.Context, id string) (*models.Zone, error) {
+	zone, err := zs.findZone(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := zs.repository.DeleteZone(ctx, zone.ID); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrZoneNotFound
+		}
+		return nil, err
+	}
+	return zone, zs.Load(ctx)
+}
+
+// findZone ищет зону в кэше по ID из запроса
+func (zs *zoneService) findZone(id string) (*models.Zone, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrZoneNotFound
+	}
+	zs.mutex.RLock()
+	defer zs.mutex.RUnlock()
+	for _, zone := range zs.zones {
+		if zone.ID == objectID {
+			return &zone, nil
+		}
+	}
+	return nil, ErrZoneNotFound
+}
+
+// buildZone проверяет запрос и собирает из него зону
+func (zs *zoneService) buildZone(req ZoneRequest) (*models.Zone, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || utf8.RuneCountInString(req.Name) > maxZoneName {
+		return nil, ErrInvalidZone
+	}
+	if req.StartsAt != nil && req.EndsAt != nil && !req.StartsAt.Before(*req.EndsAt) {
+		return nil, ErrInvalidZone
+	}
+
+	width, height := zs.config.CanvasWidth, zs.config.CanvasHeight
+	zone := &models.Zone{
+		Name:         req.Name,
+		Shape:        req.Shape,
+		StartsAt:     req.StartsAt,
+		EndsAt:       req.EndsAt,
+		AllowedTeams: []string{},
+		UpdatedAt:    time.Now().UTC(),
+	}
+	switch req.Shape {
+	case models.ZoneRect:
+		r := req.Region
+		if r == nil || r.Width <= 0 || r.Height <= 0 || r.X < 0 || r.Y < 0 ||
+			r.X+r.Width > width || r.Y+r.Height > height {
+			return nil, ErrInvalidZone
+		}
+		zone.Region = r
+	case models.ZonePolygon:
+		if len(req.Polygon) < 3 || len(req.Polygon) > maxPolygonVertices {
+			return nil, ErrInvalidZone
+		}
+		// Вершины - углы клеток, поэтому допустимы и координаты width/height
+		for _, vertex := range req.Polygon {
+			if vertex.X < 0 || vertex.Y < 0 || vertex.X > width || vertex.Y > height {
+				return nil, ErrInvalidZone
+			}
+		}
+		zone.Polygon = req.Polygon
+		if bounds := zone.Bounds(); bounds.Width == 0 || bounds.Height == 0 {
+			return nil, ErrInvalidZone
+		}
+	default:
+		return nil, ErrInvalidZone
+	}
+
+	for _, teamID := range req.AllowedTeams {
+		if !primitive.IsValidObjectID(teamID) {
+			return nil, ErrInvalidZone
+		}
+		if !zone.Allows(teamID) {
+			zone.AllowedTeams = append(zone.AllowedTeams, teamID)
+		}
+	}
+	return zone, nil
+}
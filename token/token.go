@@ -0,0 +1,209 @@
+// Package token выпускает и проверяет JWT. Используется и при входе
+// (controllers), и при проверке запросов (middlewares).
+//
+// Ключей может быть несколько, каждый со своим kid: токены подписываются
+// активным ключом, а проверяются любым из известных. Для ротации новый ключ
+// добавляется в JWT_KEYS и делается активным, а старый остаётся в списке,
+// пока не истекут выданные им токены.
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"your_project/config"
+	"your_project/models"
+
+	"github.com/golang-jwt/jwt"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims - содержимое токена. Id (jti) уникален для каждого токена: по нему
+// токен можно отозвать, не трогая остальные
+type Claims struct {
+	PublicKey string      `json:"publicKey"`
+	SessionID string      `json:"sid"`
+	Role      models.Role `json:"role"`
+	jwt.StandardClaims
+}
+
+type signingKey struct {
+	sign   interface{} // nil - ключ только для проверки
+	verify interface{}
+}
+
+type Manager struct {
+	ttl       time.Duration
+	method    jwt.SigningMethod
+	keys      map[string]signingKey
+	activeKID string
+}
+
+// NewManager загружает ключи из конфигурации. Для HS256 значение ключа - сам
+// секрет, для EdDSA и RS256 - путь к PEM-файлу с закрытым ключом (подпись и
+// проверка) или открытым (только проверка)
+func NewManager(cfg *config.Config) (*Manager, error) {
+	m := &Manager{
+		ttl:  cfg.AccessTokenTTL,
+		keys: make(map[string]signingKey),
+	}
+
+	switch cfg.JWTAlgorithm {
+	case "HS256":
+		m.method = jwt.SigningMethodHS256
+	case "EdDSA":
+		m.method = jwt.SigningMethodEdDSA
+	case "RS256":
+		m.method = jwt.SigningMethodRS256
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", cfg.JWTAlgorithm)
+	}
+
+	for i, entry := range cfg.JWTKeys {
+		kid, value, ok := strings.Cut(entry, ":")
+		if !ok || kid == "" || value == "" {
+			// Без ":" в kid окажется сам секрет, поэтому запись называем только по номеру
+			return nil, fmt.Errorf("JWT key #%d must look like kid:value", i+1)
+		}
+		key, err := m.loadKey(value)
+		if err != nil {
+			return nil, fmt.Errorf("JWT key %q: %w", kid, err)
+		}
+		m.keys[kid] = key
+	}
+
+	m.activeKID = cfg.JWTActiveKeyID
+	if m.activeKID == "" && len(cfg.JWTKeys) > 0 {
+		m.activeKID, _, _ = strings.Cut(cfg.JWTKeys[0], ":")
+	}
+	active, ok := m.keys[m.activeKID]
+	if !ok {
+		return nil, fmt.Errorf("active JWT key %q is not configured", m.activeKID)
+	}
+	if active.sign == nil {
+		return nil, fmt.Errorf("active JWT key %q has no private key", m.activeKID)
+	}
+	return m, nil
+}
+
+func (m *Manager) loadKey(value string) (signingKey, error) {
+	if m.method == jwt.SigningMethodHS256 {
+		secret := []byte(value)
+		return signingKey{sign: secret, verify: secret}, nil
+	}
+
+	pemBytes, err := os.ReadFile(value)
+	if err != nil {
+		return signingKey{}, err
+	}
+
+	if m.method == jwt.SigningMethodEdDSA {
+		if private, err := jwt.ParseEdPrivateKeyFromPEM(pemBytes); err == nil {
+			edKey, ok := private.(ed25519.PrivateKey)
+			if !ok {
+				return signingKey{}, errors.New("not an Ed25519 key")
+			}
+			return signingKey{sign: edKey, verify: edKey.Public()}, nil
+		}
+		public, err := jwt.ParseEdPublicKeyFromPEM(pemBytes)
+		if err != nil {
+			return signingKey{}, err
+		}
+		return signingKey{verify: public}, nil
+	}
+
+	if private, err := jwt.ParseRSAPrivateKeyFromPEM(pemBytes); err == nil {
+		return signingKey{sign: private, verify: &private.PublicKey}, nil
+	}
+	public, err := jwt.ParseRSAPublicKeyFromPEM(pemBytes)
+	if err != nil {
+		return signingKey{}, err
+	}
+	return signingKey{verify: public}, nil
+}
+
+// Issue подписывает активным ключом короткоживущий токен: он истекает через
+// AccessTokenTTL, но не позже notAfter (конца сессии). Возвращает токен и время истечения
+func (m *Manager) Issue(publicKey, sessionID string, role models.Role, notAfter time.Time) (string, time.Time, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiresAt := time.Now().Add(m.ttl)
+	if expiresAt.After(notAfter) {
+		expiresAt = notAfter
+	}
+
+	claims := Claims{
+		PublicKey: publicKey,
+		SessionID: sessionID,
+		Role:      role,
+		StandardClaims: jwt.StandardClaims{
+			Id:        hex.EncodeToString(id),
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: expiresAt.Unix(),
+		},
+	}
+
+	t := jwt.NewWithClaims(m.method, claims)
+	t.Header["kid"] = m.activeKID
+	signed, err := t.SignedString(m.keys[m.activeKID].sign)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+// Parse проверяет подпись, алгоритм и срок действия токена
+func (m *Manager) Parse(tokenStr string) (*Claims, error) {
+	var claims Claims
+	t, err := jwt.ParseWithClaims(tokenStr, &claims, func(t *jwt.Token) (interface{}, error) {
+		// Алгоритм должен совпадать с настроенным, иначе возможна подмена (например, на HS256 с открытым ключом)
+		if t.Method.Alg() != m.method.Alg() {
+			return nil, ErrInvalidToken
+		}
+		kid, _ := t.Header["kid"].(string)
+		key, ok := m.keys[kid]
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+		return key.verify, nil
+	})
+	if err != nil || !t.Valid {
+		return nil, ErrInvalidToken
+	}
+	if claims.PublicKey == "" || claims.SessionID == "" || claims.Id == "" {
+		return nil, ErrInvalidToken
+	}
+	if !claims.Role.Valid() {
+		claims.Role = models.RolePlayer
+	}
+	return &claims, nil
+}
+
+// Expiry возвращает время истечения токена
+func (c *Claims) Expiry() time.Time {
+	return time.Unix(c.ExpiresAt, 0)
+}
+
+// publicKeys возвращает открытые ключи по kid; для HS256 публиковать нечего
+func (m *Manager) publicKeys() map[string]crypto.PublicKey {
+	keys := make(map[string]crypto.PublicKey)
+	for kid, key := range m.keys {
+		switch k := key.verify.(type) {
+		case ed25519.PublicKey, *rsa.PublicKey:
+			keys[kid] = k
+		}
+	}
+	return keys
+}
@@ -3,10 +3,16 @@ package websocket
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
+	"your_project/codec"
+	"your_project/middlewares"
 	"your_project/models"
+	"your_project/services"
 
 	"github.com/gorilla/websocket"
 )
@@ -15,51 +21,94 @@ const (
 	writeWait      = 10 * time.Second
 	pongWait       = 60 * time.Second
 	pingPeriod     = (pongWait * 9) / 10
-	maxMessageSize = 512
+	maxMessageSize = 2048 // С запасом на сообщение "auth" с JWT
 )
 
 var Upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool { return true },
+	// Выбирается первый из предложенных клиентом: бинарный формат важнее подпротокола
+	// с токеном, которого клиент тоже ждёт в ответе, если передал токен через Sec-WebSocket-Protocol
+	Subprotocols: []string{codec.Subprotocol, middlewares.WebSocketTokenProtocol},
+}
+
+var (
+	// errNotAuthenticated - попытка поставить пиксель без токена
+	errNotAuthenticated = errors.New("placing pixels requires authentication")
+	// errTokenExpired - токен соединения истёк; клиент может прислать новый сообщением "auth"
+	errTokenExpired = errors.New("token expired, send a fresh token with an auth message")
+	// errMissingScope - у API-ключа нет права ставить пиксели
+	errMissingScope = errors.New("API key is missing the pixels:write scope")
+)
+
+// frame - сообщение в очереди на отправку клиенту
+type frame struct {
+	data   []byte
+	binary bool
 }
 
 type Client struct {
 	conn *websocket.Conn
 	hub  *Hub
-	send chan []byte
+	send chan frame
+	auth *middlewares.Authenticator // Проверяет токены из сообщений "auth"
+
+	// Личность клиента меняется сообщением "auth", поэтому читается под mu.
+	// Пиксели приписываются её кошельку; без кошелька клиент только смотрит.
+	// При отзыве её сессии или API-ключа соединение закрывается, а после
+	// истечения токена рисовать нельзя до нового "auth"
+	mu   sync.Mutex
+	user middlewares.Identity
+
+	receive bool // Получает ли клиент рассылку обновлений холста
+	legacy  bool // Клиент старых эндпоинтов /ws/send и /ws/receive
+	binary  bool // Клиент выбрал подпротокол codec.Subprotocol
+	// resumeFrom - последнее обновление, которое видел переподключившийся клиент
+	resumeFrom *ResumePoint
 }
 
-func NewSendClient(conn *websocket.Conn, hub *Hub) *Client {
-	client := &Client{
-		conn: conn,
-		hub:  hub,
-		send: make(chan []byte, 256),
-	}
+// ResumePoint - эпоха сервера и номер последнего полученного обновления
+type ResumePoint struct {
+	Epoch string
+	Seq   uint64
+}
 
-	go client.readPump() // Чтение данных
-	return client
+type ClientOptions struct {
+	Identity   *middlewares.Identity // nil - анонимный зритель
+	Auth       *middlewares.Authenticator
+	Receive    bool
+	Legacy     bool
+	ResumeFrom *ResumePoint
 }
 
-func NewReceiveClient(conn *websocket.Conn, hub *Hub) *Client {
+// NewClient регистрирует клиента в хабе и запускает чтение и запись для нового соединения
+func NewClient(conn *websocket.Conn, hub *Hub, opts ClientOptions) *Client {
 	client := &Client{
-		conn: conn,
-		hub:  hub,
-		send: make(chan []byte, 256),
+		conn:       conn,
+		hub:        hub,
+		send:       make(chan frame, 256),
+		auth:       opts.Auth,
+		receive:    opts.Receive,
+		legacy:     opts.Legacy,
+		resumeFrom: opts.ResumeFrom,
+		binary:     conn.Subprotocol() == codec.Subprotocol,
+	}
+	if opts.Identity != nil {
+		client.user = *opts.Identity
 	}
 
+	// Регистрация до запуска насосов: иначе readPump может снять клиента
+	// раньше, чем хаб о нём узнает, и клиент останется в хабе навсегда
+	hub.Register(client)
+
+	go client.readPump()  // Чтение сообщений клиента
 	go client.writePump() // Отправка данных клиенту
 	return client
 }
 
 func (c *Client) readPump() {
 	defer func() {
-		// Убедитесь, что используется правильный метод для снятия регистрации
-		if c.hub != nil {
-			if _, isSendClient := c.hub.sendClients[c]; isSendClient {
-				c.hub.UnregisterSendClient(c)
-			} else if _, isReceiveClient := c.hub.receiveClients[c]; isReceiveClient {
-				c.hub.UnregisterReceiveClient(c)
-			}
-		}
+		// Снимаем регистрацию только здесь: после этого хаб закроет send и writePump завершится
+		c.hub.Unregister(c)
 		c.conn.Close()
 	}()
 
@@ -71,7 +120,7 @@ func (c *Client) readPump() {
 	})
 
 	for {
-		_, message, err := c.conn.ReadMessage()
+		messageType, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				c.hub.Logger.Printf("WebSocket read error: %v", err)
@@ -79,63 +128,223 @@ func (c *Client) readPump() {
 			break
 		}
 
+		if messageType == websocket.BinaryMessage {
+			c.handleBinaryPlace(message)
+			continue
+		}
+
 		// Обработка входящих сообщений
-		var msg map[string]interface{}
+		var msg ClientMessage
 		if err := json.Unmarshal(message, &msg); err != nil {
-			c.hub.Logger.Println("Error unmarshaling message:", err)
+			c.replyError("", ErrorBadRequest, "malformed message")
+			continue
+		}
+		if msg.V != 0 && msg.V != ProtocolVersion {
+			c.replyError(msg.ID, ErrorUnsupportedVersion, "unsupported protocol version")
 			continue
 		}
 
-		if msg["type"] == "update" {
-			pixelData := msg["pixel"].(map[string]interface{})
-			pixel := models.Pixel{
-				X:     int(pixelData["x"].(float64)),
-				Y:     int(pixelData["y"].(float64)),
-				Color: pixelData["color"].(string),
-			}
+		switch msg.Type {
+		case TypePlace, typeLegacyUpdate:
+			c.handlePlace(msg)
+		case TypeAuth:
+			c.handleAuth(msg)
+		case TypePing:
+			c.reply(PongMessage{V: ProtocolVersion, Type: TypePong, ID: msg.ID})
+		default:
+			c.replyError(msg.ID, ErrorUnknownType, "unknown message type")
+		}
+	}
+}
 
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
+func (c *Client) handlePlace(msg ClientMessage) {
+	if msg.Pixel == nil || msg.Pixel.X == nil || msg.Pixel.Y == nil {
+		c.replyError(msg.ID, ErrorBadRequest, "pixel with x and y is required")
+		return
+	}
 
-			if err := c.hub.pixelService.UpsertPixel(ctx, pixel); err != nil {
-				c.hub.Logger.Println("Error upserting pixel:", err)
-				continue
-			}
+	// Цвет можно передать строкой "#RRGGBB" или индексом палитры
+	pixel, err := c.place(models.Pixel{
+		X:          *msg.Pixel.X,
+		Y:          *msg.Pixel.Y,
+		Color:      msg.Pixel.Color,
+		ColorIndex: msg.Pixel.ColorIndex,
+	})
+	if err != nil {
+		var cooldownErr *services.CooldownError
+		if errors.As(err, &cooldownErr) {
+			c.reply(CooldownMessage{
+				V:           ProtocolVersion,
+				Type:        TypeCooldown,
+				ID:          msg.ID,
+				RemainingMs: cooldownErr.Remaining.Milliseconds(),
+			})
+			return
+		}
+		c.replyPlaceError(msg.ID, err)
+		return
+	}
 
-			updateMessage := map[string]interface{}{
-				"type":  "update",
-				"pixel": pixel,
-			}
+	// Старые клиенты /ws/send подтверждений не ждут
+	if !c.legacy {
+		c.reply(AckMessage{V: ProtocolVersion, Type: TypeAck, ID: msg.ID, Pixel: pixel})
+	}
+}
 
-			message, err := json.Marshal(updateMessage)
-			if err != nil {
-				c.hub.Logger.Println("Error marshaling update message:", err)
-				continue
-			}
+// handleAuth принимает новый токен: так долгоживущее соединение продлевает
+// право рисовать после /api/refresh, а анонимный клиент может войти
+func (c *Client) handleAuth(msg ClientMessage) {
+	if c.auth == nil || msg.Token == "" {
+		c.replyError(msg.ID, ErrorBadRequest, "token is required")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	identity, err := c.auth.AuthenticateToken(ctx, msg.Token)
+	cancel()
+	if err != nil {
+		var banErr *services.BanError
+		if errors.As(err, &banErr) {
+			c.replyError(msg.ID, ErrorBanned, banErr.Error())
+		} else {
+			c.replyError(msg.ID, ErrorUnauthorized, "invalid token")
+		}
+		return
+	}
+
+	c.mu.Lock()
+	if c.user.PublicKey != "" && c.user.PublicKey != identity.PublicKey {
+		c.mu.Unlock()
+		c.replyError(msg.ID, ErrorUnauthorized, "token belongs to another wallet")
+		return
+	}
+	c.user = *identity
+	c.mu.Unlock()
+
+	c.reply(AuthenticatedMessage{
+		V:         ProtocolVersion,
+		Type:      TypeAuthenticated,
+		ID:        msg.ID,
+		PublicKey: identity.PublicKey,
+		ExpiresAt: identity.ExpiresAt,
+	})
+}
+
+// identity возвращает текущую личность клиента
+func (c *Client) identity() middlewares.Identity {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.user
+}
 
-			c.hub.Broadcast(message)
+// handleBinaryPlace обрабатывает codec.FramePlace. Подтверждение и cooldown
+// отправляются бинарными фреймами, ошибки - JSON с id в десятичном виде
+func (c *Client) handleBinaryPlace(data []byte) {
+	id, record, err := codec.DecodePlace(data)
+	if err != nil {
+		c.replyError("", ErrorBadRequest, "malformed binary frame")
+		return
+	}
+
+	colorIndex := int(record.Color)
+	_, err = c.place(models.Pixel{
+		X:          int(record.X),
+		Y:          int(record.Y),
+		ColorIndex: &colorIndex,
+	})
+	if err != nil {
+		var cooldownErr *services.CooldownError
+		if errors.As(err, &cooldownErr) {
+			c.hub.sendTo(c, frame{data: codec.EncodeCooldown(id, cooldownErr.Remaining), binary: true})
+			return
 		}
+		c.replyPlaceError(strconv.FormatUint(uint64(id), 10), err)
+		return
+	}
+
+	c.hub.sendTo(c, frame{data: codec.EncodeAck(id), binary: true})
+}
+
+// place сохраняет пиксель от имени кошелька клиента и рассылает его
+func (c *Client) place(pixel models.Pixel) (models.Pixel, error) {
+	user := c.identity()
+	if user.PublicKey == "" {
+		return models.Pixel{}, errNotAuthenticated
+	}
+	if !user.ExpiresAt.IsZero() && time.Now().After(user.ExpiresAt) {
+		return models.Pixel{}, errTokenExpired
+	}
+	if !user.Allows(models.ScopePixelsWrite) {
+		return models.Pixel{}, errMissingScope
+	}
+
+	// Пиксели от API-ключа помечаются как бот, но кулдаун общий с кошельком-владельцем
+	pixel.Bot = user.Bot()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	pixel, err := c.hub.pixelService.PlacePixel(ctx, user.PublicKey, pixel)
+	cancel()
+	if err != nil {
+		return models.Pixel{}, err
+	}
+
+	c.hub.PublishPixel(pixel)
+	return pixel, nil
+}
+
+// replyPlaceError сообщает клиенту, почему пиксель не принят
+func (c *Client) replyPlaceError(id string, err error) {
+	switch {
+	case errors.Is(err, errNotAuthenticated), errors.Is(err, errMissingScope):
+		c.replyError(id, ErrorUnauthorized, err.Error())
+	case errors.Is(err, errTokenExpired):
+		c.replyError(id, ErrorTokenExpired, err.Error())
+	case errors.As(err, new(*services.BanError)):
+		c.replyError(id, ErrorBanned, err.Error())
+	case errors.Is(err, services.ErrOutOfBounds):
+		c.replyError(id, ErrorOutOfBounds, err.Error())
+	case errors.Is(err, services.ErrInvalidColor):
+		c.replyError(id, ErrorInvalidColor, err.Error())
+	case errors.As(err, new(*services.ProtectedError)):
+		c.replyError(id, ErrorProtected, err.Error())
+	case errors.As(err, new(*services.TerritoryError)):
+		c.replyError(id, ErrorTerritory, err.Error())
+	default:
+		c.hub.Logger.Println("Error upserting pixel:", err)
+		c.replyError(id, ErrorInternal, "failed to place pixel")
 	}
 }
 
+// reply отправляет сообщение только этому клиенту
+func (c *Client) reply(payload interface{}) {
+	message, err := json.Marshal(payload)
+	if err != nil {
+		c.hub.Logger.Println("Error marshaling reply:", err)
+		return
+	}
+	c.hub.sendTo(c, frame{data: message})
+}
+
+func (c *Client) replyError(id, code, reason string) {
+	c.reply(ErrorMessage{
+		V:     ProtocolVersion,
+		Type:  TypeError,
+		ID:    id,
+		Code:  code,
+		Error: reason,
+	})
+}
+
 func (c *Client) writePump() {
 	ticker := time.NewTicker(pingPeriod)
 	defer func() {
 		ticker.Stop()
-		// Убедитесь, что используется правильный метод для снятия регистрации
-		if c.hub != nil {
-			if _, isSendClient := c.hub.sendClients[c]; isSendClient {
-				c.hub.UnregisterSendClient(c)
-			} else if _, isReceiveClient := c.hub.receiveClients[c]; isReceiveClient {
-				c.hub.UnregisterReceiveClient(c)
-			}
-		}
+		// Закрытие соединения завершит readPump, который и снимет регистрацию
 		c.conn.Close()
 	}()
 
 	for {
 		select {
-		case message, ok := <-c.send:
+		case f, ok := <-c.send:
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
 				// Канал закрыт
@@ -143,20 +352,7 @@ func (c *Client) writePump() {
 				return
 			}
 
-			writer, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
-				return
-			}
-			writer.Write(message)
-
-			// Добавляем очередные сообщения
-			n := len(c.send)
-			for i := 0; i < n; i++ {
-				writer.Write([]byte{'\n'})
-				writer.Write(<-c.send)
-			}
-
-			if err := writer.Close(); err != nil {
+			if err := c.writeFrames(f); err != nil {
 				return
 			}
 
@@ -168,3 +364,41 @@ func (c *Client) writePump() {
 		}
 	}
 }
+
+// writeFrames отправляет фрейм вместе со всеми уже накопившимися в очереди.
+// Подряд идущие текстовые сообщения склеиваются через "\n" в один фрейм,
+// бинарные всегда уходят отдельными фреймами
+func (c *Client) writeFrames(first frame) error {
+	frames := []frame{first}
+	for n := len(c.send); n > 0; n-- {
+		f, ok := <-c.send
+		if !ok {
+			break
+		}
+		frames = append(frames, f)
+	}
+
+	for i := 0; i < len(frames); {
+		if frames[i].binary {
+			if err := c.conn.WriteMessage(websocket.BinaryMessage, frames[i].data); err != nil {
+				return err
+			}
+			i++
+			continue
+		}
+
+		writer, err := c.conn.NextWriter(websocket.TextMessage)
+		if err != nil {
+			return err
+		}
+		writer.Write(frames[i].data)
+		for i++; i < len(frames) && !frames[i].binary; i++ {
+			writer.Write([]byte{'\n'})
+			writer.Write(frames[i].data)
+		}
+		if err := writer.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
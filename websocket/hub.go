@@ -4,126 +4,454 @@ import (
 	"context"
 	"encoding/json"
 	"log"
+	"strconv"
 	"sync"
 	"time"
-	"your_project/repositories"
 
-	"go.mongodb.org/mongo-driver/mongo"
+	"your_project/canvas"
+	"your_project/codec"
+	"your_project/models"
 	"your_project/services"
 )
 
+const (
+	// updateLogSize - сколько последних обновлений хранится для досылки после переподключения
+	updateLogSize = 1024
+	// batchInterval - как часто бинарным клиентам уходит пачка накопившихся обновлений
+	batchInterval = 50 * time.Millisecond
+	// maxLiveUpdates - пачку больше этого (откат региона) не рассылаем по пикселю:
+	// она переполнит буферы клиентов, вместо неё получатели качают свежий снапшот
+	maxLiveUpdates = 128
+	// liveLeaderboardSize - сколько команд и игроков в рассылаемой таблице лидеров
+	liveLeaderboardSize = 10
+)
+
 type Hub struct {
-	sendClients       map[*Client]bool // Клиенты для отправки
-	receiveClients    map[*Client]bool // Клиенты для получения
-	broadcast         chan []byte
-	registerSend      chan *Client
-	registerReceive   chan *Client
-	unregisterSend    chan *Client
-	unregisterReceive chan *Client
-	pixelService      services.PixelService
-	Logger            *log.Logger
-	mutex             sync.RWMutex
-}
-
-func NewHub(mongoClient *mongo.Client, dbName string) *Hub {
-	db := mongoClient.Database(dbName)
-	pixelRepo := repositories.NewPixelRepository(db)
-	pixelService := services.NewPixelService(pixelRepo)
+	clients      map[*Client]bool
+	broadcast    chan []byte
+	register     chan *Client
+	unregister   chan *Client
+	direct       chan directMessage
+	disconnect   chan disconnectRequest
+	publish      chan models.Pixel
+	publishMany  chan []models.Pixel
+	canvas       chan struct{}
+	leaderboard  chan []byte
+	pixelService services.PixelService
+	board        *canvas.Board // Состояние холста в памяти для снапшотов
+	updates      *updateLog
+	batch        []codec.Record // Обновления для бинарных клиентов, ждущие отправки
+	batchSeq     uint64         // Номер первого обновления в batch
+	epoch        string         // Отличает запуски сервера: seq начинается заново при рестарте
+	Logger       *log.Logger
+	mutex        sync.RWMutex
+}
+
+// disconnectRequest - закрыть соединения кошелька; с sessionID или apiKeyID -
+// только открытые с этой сессией или этим ключом
+type disconnectRequest struct {
+	publicKey string
+	sessionID string
+	apiKeyID  string
+}
+
+// directMessage - сообщение одному клиенту (ответ на его действие)
+type directMessage struct {
+	client *Client
+	frame  frame
+}
 
+func NewHub(pixelService services.PixelService) *Hub {
+	canvasInfo := pixelService.Canvas()
 	return &Hub{
-		sendClients:       make(map[*Client]bool),
-		receiveClients:    make(map[*Client]bool),
-		broadcast:         make(chan []byte),
-		registerSend:      make(chan *Client),
-		registerReceive:   make(chan *Client),
-		unregisterSend:    make(chan *Client),
-		unregisterReceive: make(chan *Client),
-		pixelService:      pixelService,
-		Logger:            log.Default(),
+		clients:      make(map[*Client]bool),
+		broadcast:    make(chan []byte),
+		register:     make(chan *Client),
+		unregister:   make(chan *Client),
+		direct:       make(chan directMessage),
+		disconnect:   make(chan disconnectRequest),
+		publish:      make(chan models.Pixel),
+		publishMany:  make(chan []models.Pixel),
+		canvas:       make(chan struct{}),
+		leaderboard:  make(chan []byte),
+		pixelService: pixelService,
+		board:        canvas.NewBoard(canvasInfo.Width, canvasInfo.Height),
+		updates:      newUpdateLog(updateLogSize),
+		epoch:        strconv.FormatInt(time.Now().UnixNano(), 36),
+		Logger:       log.Default(),
+	}
+}
+
+// LoadCanvas заполняет холст в памяти сохранёнными пикселями. Вызывается до Run
+func (h *Hub) LoadCanvas(ctx context.Context) error {
+	pixels, err := h.pixelService.GetAllPixels(ctx)
+	if err != nil {
+		return err
+	}
+	palette := h.pixelService.Palette()
+	for _, pixel := range pixels {
+		index := -1
+		if pixel.ColorIndex != nil {
+			index = *pixel.ColorIndex
+		} else if c, err := canvas.ParseHexColor(pixel.Color); err == nil {
+			// Цвет не из палитры (до миграции) - показываем ближайший
+			index = palette.Nearest(c)
+		}
+		if index >= 0 {
+			h.board.Set(pixel.X, pixel.Y, byte(index))
+		}
 	}
+	return nil
+}
+
+func (h *Hub) Board() *canvas.Board {
+	return h.board
+}
+
+// Epoch отличает этот запуск сервера; версии холста сравнимы только в одной эпохе
+func (h *Hub) Epoch() string {
+	return h.epoch
 }
 
 func (h *Hub) Run() {
+	ticker := time.NewTicker(batchInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
-		case client := <-h.registerSend:
+		case client := <-h.register:
 			h.mutex.Lock()
-			h.sendClients[client] = true
+			h.clients[client] = true
 			h.mutex.Unlock()
-		case client := <-h.unregisterSend:
-			h.mutex.Lock()
-			if _, ok := h.sendClients[client]; ok {
-				delete(h.sendClients, client)
-				close(client.send)
+			if client.receive && !h.resume(client) {
+				go h.sendInitialState(client)
 			}
-			h.mutex.Unlock()
-		case client := <-h.registerReceive:
-			h.mutex.Lock()
-			h.receiveClients[client] = true
-			h.mutex.Unlock()
-			go h.sendInitialState(client)
-		case client := <-h.unregisterReceive:
+		case client := <-h.unregister:
 			h.mutex.Lock()
-			if _, ok := h.receiveClients[client]; ok {
-				delete(h.receiveClients, client)
+			if _, ok := h.clients[client]; ok {
+				delete(h.clients, client)
 				close(client.send)
 			}
 			h.mutex.Unlock()
-		case message := <-h.broadcast:
-			h.mutex.RLock()
-			for client := range h.receiveClients { // Только клиенты для получения
+		case dm := <-h.direct:
+			// Канал send закрывается только здесь, поэтому проверяем регистрацию в этой же горутине
+			h.mutex.Lock()
+			if h.clients[dm.client] {
 				select {
-				case client.send <- message:
+				case dm.client.send <- dm.frame:
 				default:
-					close(client.send)
-					delete(h.receiveClients, client)
 				}
 			}
-			h.mutex.RUnlock()
+			h.mutex.Unlock()
+		case req := <-h.disconnect:
+			h.disconnectClients(req)
+		case pixel := <-h.publish:
+			h.publishPixel(pixel)
+		case pixels := <-h.publishMany:
+			h.publishPixels(pixels)
+		case <-h.canvas:
+			h.broadcastCanvas()
+		case message := <-h.leaderboard:
+			// Старый протокол таблицу лидеров не знает
+			h.broadcastFrame(frame{data: message}, func(c *Client) bool { return !c.legacy })
+		case message := <-h.broadcast:
+			h.broadcastMessage(message)
+		case <-ticker.C:
+			h.flushBatch()
+		}
+	}
+}
+
+func (h *Hub) broadcastMessage(message []byte) {
+	h.broadcastFrame(frame{data: message}, func(*Client) bool { return true })
+}
+
+// disconnectClients закрывает соединения отозванной сессии или всего кошелька.
+// Клиент получает ошибку unauthorized, после чего writePump закрывает соединение
+func (h *Hub) disconnectClients(req disconnectRequest) {
+	message, err := json.Marshal(ErrorMessage{
+		V:     ProtocolVersion,
+		Type:  TypeError,
+		Code:  ErrorUnauthorized,
+		Error: "credentials revoked",
+	})
+	if err != nil {
+		h.Logger.Println("Error marshaling disconnect message:", err)
+		return
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for client := range h.clients {
+		user := client.identity()
+		if user.PublicKey != req.publicKey ||
+			(req.sessionID != "" && user.SessionID != req.sessionID) ||
+			(req.apiKeyID != "" && user.APIKeyID != req.apiKeyID) {
+			continue
+		}
+		select {
+		case client.send <- frame{data: message}:
+		default:
+		}
+		close(client.send)
+		delete(h.clients, client)
+	}
+}
+
+// broadcastFrame рассылает фрейм получателям, для которых include возвращает true
+func (h *Hub) broadcastFrame(f frame, include func(*Client) bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for client := range h.clients {
+		if !client.receive || !include(client) {
+			continue
+		}
+		select {
+		case client.send <- f:
+		default:
+			close(client.send)
+			delete(h.clients, client)
+		}
+	}
+}
+
+// publishPixel присваивает обновлению номер (он же версия холста), запоминает
+// его в буфере и рассылает получателям. Пиксель без ColorIndex очищает клетку.
+// Вызывается только из Run
+func (h *Hub) publishPixel(pixel models.Pixel) {
+	color := canvas.Empty
+	if pixel.ColorIndex != nil {
+		color = byte(*pixel.ColorIndex)
+	}
+	seq := h.board.Set(pixel.X, pixel.Y, color)
+
+	message, err := json.Marshal(UpdateMessage{
+		V:     ProtocolVersion,
+		Type:  TypeUpdate,
+		Seq:   seq,
+		Pixel: pixel,
+	})
+	if err != nil {
+		h.Logger.Println("Error marshaling update message:", err)
+		return
+	}
+
+	h.updates.append(seq, message)
+	h.broadcastFrame(frame{data: message}, func(c *Client) bool { return !c.binary })
+
+	if len(h.batch) == 0 {
+		h.batchSeq = seq
+	}
+	h.batch = append(h.batch, codec.Record{X: uint16(pixel.X), Y: uint16(pixel.Y), Color: color})
+	if len(h.batch) == codec.MaxRecords {
+		h.flushBatch()
+	}
+}
+
+// publishPixels применяет пачку пикселей. Небольшая пачка расходится обычными
+// обновлениями; большая меняет холст целиком, сбрасывает буфер досылки
+// (пропущенное уже не восстановить по обновлениям) и рассылает получателям
+// новый снапшот. Вызывается только из Run
+func (h *Hub) publishPixels(pixels []models.Pixel) {
+	if len(pixels) <= maxLiveUpdates {
+		for _, pixel := range pixels {
+			h.publishPixel(pixel)
+		}
+		return
+	}
+
+	h.flushBatch()
+	for _, pixel := range pixels {
+		color := canvas.Empty
+		if pixel.ColorIndex != nil {
+			color = byte(*pixel.ColorIndex)
 		}
+		h.board.Set(pixel.X, pixel.Y, color)
+	}
+	h.updates.reset()
+
+	for _, legacy := range []bool{false, true} {
+		message, err := h.initialStateMessage(legacy)
+		if err != nil {
+			h.Logger.Println("Error marshaling snapshot message:", err)
+			return
+		}
+		h.broadcastFrame(frame{data: message}, func(c *Client) bool { return c.legacy == legacy })
+	}
+}
+
+// broadcastCanvas рассылает получателям новые метаданные холста. Старый
+// протокол таких сообщений не знает
+func (h *Hub) broadcastCanvas() {
+	message, err := json.Marshal(CanvasMessage{
+		V:      ProtocolVersion,
+		Type:   TypeCanvas,
+		Canvas: h.pixelService.Canvas(),
+	})
+	if err != nil {
+		h.Logger.Println("Error marshaling canvas message:", err)
+		return
 	}
+	h.broadcastFrame(frame{data: message}, func(c *Client) bool { return !c.legacy })
 }
 
-func (h *Hub) RegisterSendClient(client *Client) {
-	h.registerSend <- client
+// flushBatch отправляет накопившиеся обновления бинарным клиентам одним фреймом
+func (h *Hub) flushBatch() {
+	if len(h.batch) == 0 {
+		return
+	}
+	data := codec.EncodeUpdates(h.batchSeq, h.batch)
+	h.batch = h.batch[:0]
+	h.broadcastFrame(frame{data: data, binary: true}, func(c *Client) bool { return c.binary })
 }
 
-func (h *Hub) RegisterReceiveClient(client *Client) {
-	h.registerReceive <- client
+// resume досылает переподключившемуся клиенту пропущенные обновления.
+// Возвращает false, если это невозможно и клиенту нужен снапшот
+func (h *Hub) resume(client *Client) bool {
+	if client.resumeFrom == nil || client.resumeFrom.Epoch != h.epoch {
+		return false
+	}
+
+	seq := h.board.Version()
+	missed, ok := h.updates.since(client.resumeFrom.Seq, seq)
+	if !ok {
+		return false
+	}
+	if missed == nil {
+		missed = []json.RawMessage{}
+	}
+
+	message, err := json.Marshal(ResumeMessage{
+		V:       ProtocolVersion,
+		Type:    TypeResume,
+		Epoch:   h.epoch,
+		Seq:     seq,
+		Updates: missed,
+	})
+	if err != nil {
+		h.Logger.Println("Error marshaling resume message:", err)
+		return false
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	select {
+	case client.send <- frame{data: message}:
+		return true
+	default:
+		return false
+	}
 }
 
-func (h *Hub) UnregisterSendClient(client *Client) {
-	h.unregisterSend <- client
+func (h *Hub) Register(client *Client) {
+	h.register <- client
 }
 
-func (h *Hub) UnregisterReceiveClient(client *Client) {
-	h.unregisterReceive <- client
+func (h *Hub) Unregister(client *Client) {
+	h.unregister <- client
 }
 
 func (h *Hub) Broadcast(message []byte) {
 	h.broadcast <- message
 }
 
-func (h *Hub) sendInitialState(client *Client) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// PublishPixel применяет пиксель к холсту в памяти и рассылает его получателям
+func (h *Hub) PublishPixel(pixel models.Pixel) {
+	h.publish <- pixel
+}
 
-	pixels, err := h.pixelService.GetAllPixels(ctx)
-	if err != nil {
-		h.Logger.Println("Error fetching initial pixels:", err)
-		return
+// PublishPixels применяет пачку пикселей (например, результат отката)
+func (h *Hub) PublishPixels(pixels []models.Pixel) {
+	if len(pixels) > 0 {
+		h.publishMany <- pixels
 	}
+}
 
-	initialMessage := map[string]interface{}{
-		"type":   "initial",
-		"pixels": pixels,
+// RunLeaderboard раз в interval рассылает получателям таблицу лидеров по
+// общему числу установок. Запросы к базе идут в своей горутине, а не в Run
+func (h *Hub) RunLeaderboard(stats services.StatsService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	query := models.LeaderboardQuery{Sort: models.SortByTotal, Limit: liveLeaderboardSize}
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		teams, err := stats.GetTeamLeaderboard(ctx, query)
+		var players []models.PlayerStats
+		if err == nil {
+			players, err = stats.GetPlayerLeaderboard(ctx, query)
+		}
+		cancel()
+		if err != nil {
+			h.Logger.Println("Error loading leaderboard:", err)
+			continue
+		}
+
+		message, err := json.Marshal(LeaderboardMessage{
+			V:       ProtocolVersion,
+			Type:    TypeLeaderboard,
+			Teams:   teams,
+			Players: players,
+		})
+		if err != nil {
+			h.Logger.Println("Error marshaling leaderboard message:", err)
+			continue
+		}
+		h.leaderboard <- message
 	}
+}
+
+// BroadcastCanvas сообщает получателям, что изменились метаданные холста
+func (h *Hub) BroadcastCanvas() {
+	h.canvas <- struct{}{}
+}
+
+// DisconnectWallet закрывает все соединения кошелька (выход со всех устройств)
+func (h *Hub) DisconnectWallet(publicKey string) {
+	h.disconnect <- disconnectRequest{publicKey: publicKey}
+}
+
+// DisconnectSession закрывает соединения, открытые с токеном указанной сессии
+func (h *Hub) DisconnectSession(publicKey, sessionID string) {
+	h.disconnect <- disconnectRequest{publicKey: publicKey, sessionID: sessionID}
+}
+
+// DisconnectAPIKey закрывает соединения ботов с отозванным ключом
+func (h *Hub) DisconnectAPIKey(publicKey, apiKeyID string) {
+	h.disconnect <- disconnectRequest{publicKey: publicKey, apiKeyID: apiKeyID}
+}
 
-	message, err := json.Marshal(initialMessage)
+// sendTo отправляет фрейм одному клиенту, если он ещё подключён
+func (h *Hub) sendTo(client *Client, f frame) {
+	h.direct <- directMessage{client: client, frame: f}
+}
+
+// sendInitialState сообщает новому клиенту метаданные холста и версию снапшота.
+// Сами пиксели клиент скачивает одним блобом с /api/canvas/snapshot
+func (h *Hub) sendInitialState(client *Client) {
+	message, err := h.initialStateMessage(client.legacy)
 	if err != nil {
 		h.Logger.Println("Error marshaling initial message:", err)
 		return
 	}
 
-	client.send <- message
+	h.sendTo(client, frame{data: message})
+}
+
+func (h *Hub) initialStateMessage(legacy bool) ([]byte, error) {
+	initialMessage := SnapshotMessage{
+		V:       ProtocolVersion,
+		Type:    TypeSnapshot,
+		Epoch:   h.epoch,
+		Canvas:  h.pixelService.Canvas(),
+		Palette: h.pixelService.Palette().Colors(),
+		Snapshot: SnapshotRef{
+			URL:     "/api/canvas/snapshot",
+			Version: h.board.Version(),
+		},
+	}
+	if legacy {
+		initialMessage.Type = typeLegacyInitial
+	}
+	return json.Marshal(initialMessage)
 }
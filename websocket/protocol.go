@@ -0,0 +1,152 @@
+package websocket
+
+import (
+	"encoding/json"
+	"time"
+
+	"your_project/models"
+)
+
+// ProtocolVersion - версия схемы сообщений /ws. Клиент указывает её в поле "v";
+// сообщения без "v" считаются сообщениями старых эндпоинтов /ws/send и /ws/receive.
+// Один текстовый фрейм может содержать несколько сообщений, разделённых "\n"
+const ProtocolVersion = 1
+
+// Типы сообщений
+const (
+	// Клиент -> сервер
+	TypePlace = "place"
+	TypePing  = "ping"
+	TypeAuth  = "auth" // Новый токен для соединения, например после /api/refresh
+
+	// Сервер -> клиент
+	TypeAck      = "ack"
+	TypeError    = "error"
+	TypeUpdate   = "update"
+	TypeSnapshot = "snapshot"
+	TypeResume   = "resume"
+	TypeCooldown = "cooldown"
+	TypePong     = "pong"
+	// TypeCanvas - изменились метаданные холста (например, территории команд)
+	TypeCanvas = "canvas"
+	// TypeLeaderboard - периодическая таблица лидеров команд и игроков
+	TypeLeaderboard = "leaderboard"
+	// TypeAuthenticated - ответ на "auth"
+	TypeAuthenticated = "authenticated"
+
+	// typeLegacyUpdate - установка пикселя в старом протоколе /ws/send
+	typeLegacyUpdate = "update"
+	// typeLegacyInitial - начальное состояние в старом протоколе /ws/receive
+	typeLegacyInitial = "initial"
+)
+
+// Коды ошибок в ErrorMessage
+const (
+	ErrorBadRequest         = "bad_request"
+	ErrorUnsupportedVersion = "unsupported_version"
+	ErrorUnknownType        = "unknown_type"
+	ErrorUnauthorized       = "unauthorized"
+	ErrorTokenExpired       = "token_expired"
+	ErrorBanned             = "banned"
+	ErrorOutOfBounds        = "out_of_bounds"
+	ErrorInvalidColor       = "invalid_color"
+	ErrorProtected          = "protected" // Клетка в защищённой зоне
+	ErrorTerritory          = "territory" // Клетка на закрытой территории чужой команды
+	ErrorInternal           = "internal"
+)
+
+// ClientMessage - любое сообщение от клиента. ID произвольный и возвращается в ack/error
+type ClientMessage struct {
+	V     int           `json:"v,omitempty"`
+	Type  string        `json:"type"`
+	ID    string        `json:"id,omitempty"`
+	Pixel *PixelPayload `json:"pixel,omitempty"`
+	Token string        `json:"token,omitempty"` // Только для "auth"
+}
+
+// PixelPayload - пиксель от клиента: цвет строкой или индексом палитры
+type PixelPayload struct {
+	X          *int   `json:"x"`
+	Y          *int   `json:"y"`
+	Color      string `json:"color,omitempty"`
+	ColorIndex *int   `json:"colorIndex,omitempty"`
+}
+
+type AckMessage struct {
+	V     int          `json:"v"`
+	Type  string       `json:"type"`
+	ID    string       `json:"id,omitempty"`
+	Pixel models.Pixel `json:"pixel"`
+}
+
+type ErrorMessage struct {
+	V     int    `json:"v"`
+	Type  string `json:"type"`
+	ID    string `json:"id,omitempty"`
+	Code  string `json:"code"`
+	Error string `json:"error"`
+}
+
+type CooldownMessage struct {
+	V           int    `json:"v"`
+	Type        string `json:"type"`
+	ID          string `json:"id,omitempty"`
+	RemainingMs int64  `json:"remainingMs"`
+}
+
+type AuthenticatedMessage struct {
+	V         int       `json:"v"`
+	Type      string    `json:"type"`
+	ID        string    `json:"id,omitempty"`
+	PublicKey string    `json:"publicKey"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type PongMessage struct {
+	V    int    `json:"v"`
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
+}
+
+type UpdateMessage struct {
+	V     int          `json:"v"`
+	Type  string       `json:"type"`
+	Seq   uint64       `json:"seq"`
+	Pixel models.Pixel `json:"pixel"`
+}
+
+type SnapshotMessage struct {
+	V        int           `json:"v"`
+	Type     string        `json:"type"`
+	Epoch    string        `json:"epoch"`
+	Canvas   models.Canvas `json:"canvas"`
+	Palette  []string      `json:"palette"`
+	Snapshot SnapshotRef   `json:"snapshot"`
+}
+
+type CanvasMessage struct {
+	V      int           `json:"v"`
+	Type   string        `json:"type"`
+	Canvas models.Canvas `json:"canvas"`
+}
+
+type LeaderboardMessage struct {
+	V       int                  `json:"v"`
+	Type    string               `json:"type"`
+	Teams   []models.TeamStats   `json:"teams"`
+	Players []models.PlayerStats `json:"players"`
+}
+
+// SnapshotRef указывает, откуда скачать бинарный снапшот и какой версии он будет не ниже
+type SnapshotRef struct {
+	URL     string `json:"url"`
+	Version uint64 `json:"version"`
+}
+
+type ResumeMessage struct {
+	V       int               `json:"v"`
+	Type    string            `json:"type"`
+	Epoch   string            `json:"epoch"`
+	Seq     uint64            `json:"seq"`
+	Updates []json.RawMessage `json:"updates"`
+}
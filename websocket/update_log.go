@@ -0,0 +1,63 @@
+package websocket
+
+import "encoding/json"
+
+// updateLog - кольцевой буфер последних разосланных обновлений для досылки
+// пропущенного переподключившимся клиентам
+type updateLog struct {
+	entries []loggedUpdate
+	start   int // Индекс самой старой записи
+	size    int
+}
+
+type loggedUpdate struct {
+	seq     uint64
+	message json.RawMessage
+}
+
+func newUpdateLog(capacity int) *updateLog {
+	return &updateLog{entries: make([]loggedUpdate, capacity)}
+}
+
+func (l *updateLog) append(seq uint64, message []byte) {
+	capacity := len(l.entries)
+	if l.size < capacity {
+		l.entries[(l.start+l.size)%capacity] = loggedUpdate{seq: seq, message: message}
+		l.size++
+		return
+	}
+	l.entries[l.start] = loggedUpdate{seq: seq, message: message}
+	l.start = (l.start + 1) % capacity
+}
+
+// reset забывает все записи: после него досылка невозможна до новых обновлений
+func (l *updateLog) reset() {
+	l.start = 0
+	l.size = 0
+}
+
+// since возвращает обновления с номером больше seq. ok == false, если часть
+// из них уже вытеснена из буфера и клиенту нужен полный снапшот
+func (l *updateLog) since(seq, current uint64) ([]json.RawMessage, bool) {
+	if seq == current {
+		return nil, true
+	}
+	if seq > current || l.size == 0 {
+		return nil, false
+	}
+
+	capacity := len(l.entries)
+	oldest := l.entries[l.start].seq
+	if seq+1 < oldest {
+		return nil, false
+	}
+
+	var missed []json.RawMessage
+	for i := 0; i < l.size; i++ {
+		entry := l.entries[(l.start+i)%capacity]
+		if entry.seq > seq {
+			missed = append(missed, entry.message)
+		}
+	}
+	return missed, true
+}